@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"cmp"
+	"context"
+)
+
+// RatingSummary is the average of the rating scores of one restaurant. Average is 0
+// when Count is 0.
+type RatingSummary struct {
+	RestaurantID string
+	Average      float64
+	Count        int
+}
+
+// RatedRestaurant is a TopRatedByCity result.
+type RatedRestaurant struct {
+	Restaurant *Restaurant
+	Average    float64
+	Count      int
+}
+
+// PriceStats summarises the menu prices of one restaurant. The prices are 0 when
+// Count is 0.
+type PriceStats struct {
+	RestaurantID string
+	Min          float64
+	Max          float64
+	Average      float64
+	Count        int
+}
+
+type RestaurantAnalyticsReader interface {
+	// AverageRating fails with ErrNotFound when no restaurant has id.
+	AverageRating(ctx context.Context, id string) (*RatingSummary, error)
+	// TopRatedByCity returns the rated restaurants of city, matched case-insensitively,
+	// by descending average, then descending rating count, then ID. limit defaults to
+	// DefaultPageLimit and is capped at MaxPageLimit.
+	TopRatedByCity(ctx context.Context, city string, limit int) ([]*RatedRestaurant, error)
+	// MenuPriceStats fails with ErrNotFound when no restaurant has id.
+	MenuPriceStats(ctx context.Context, id string) (*PriceStats, error)
+}
+
+// RatingSummary computes the RatingSummary of r.
+func (r *Restaurant) RatingSummary() RatingSummary {
+	s := RatingSummary{RestaurantID: r.ID, Count: len(r.Ratings)}
+	if s.Count == 0 {
+		return s
+	}
+	var sum int
+	for _, rating := range r.Ratings {
+		sum += rating.Score
+	}
+	s.Average = float64(sum) / float64(s.Count)
+	return s
+}
+
+// MenuPriceStats computes the PriceStats of r.
+func (r *Restaurant) MenuPriceStats() PriceStats {
+	s := PriceStats{RestaurantID: r.ID, Count: len(r.Menu)}
+	if s.Count == 0 {
+		return s
+	}
+	s.Min, s.Max = r.Menu[0].Price, r.Menu[0].Price
+	var sum float64
+	for _, item := range r.Menu {
+		s.Min, s.Max = min(s.Min, item.Price), max(s.Max, item.Price)
+		sum += item.Price
+	}
+	s.Average = sum / float64(s.Count)
+	return s
+}
+
+// CompareRated orders RatedRestaurants as TopRatedByCity returns them.
+func CompareRated(a, b *RatedRestaurant) int {
+	return cmp.Or(
+		cmp.Compare(b.Average, a.Average),
+		cmp.Compare(b.Count, a.Count),
+		cmp.Compare(a.Restaurant.ID, b.Restaurant.ID),
+	)
+}
+
+// NormalizeLimit applies DefaultPageLimit to a missing limit and caps it at MaxPageLimit.
+func NormalizeLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageLimit
+	}
+	return min(limit, MaxPageLimit)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestaurantAnalytics(t *testing.T) {
+	r := &Restaurant{
+		ID:      "r1",
+		Ratings: []Rating{{Score: 5}, {Score: 2}, {Score: 4}},
+		Menu:    []MenuItem{{Price: 9.5}, {Price: 3}, {Price: 12}},
+	}
+	assert.Equal(t, RatingSummary{RestaurantID: "r1", Average: 11.0 / 3, Count: 3}, r.RatingSummary())
+	assert.Equal(t, PriceStats{RestaurantID: "r1", Min: 3, Max: 12, Average: 24.5 / 3, Count: 3}, r.MenuPriceStats())
+
+	empty := &Restaurant{ID: "r2"}
+	assert.Equal(t, RatingSummary{RestaurantID: "r2"}, empty.RatingSummary())
+	assert.Equal(t, PriceStats{RestaurantID: "r2"}, empty.MenuPriceStats())
+}
+
+func TestCompareRated(t *testing.T) {
+	rated := func(id string, avg float64, count int) *RatedRestaurant {
+		return &RatedRestaurant{Restaurant: &Restaurant{ID: id}, Average: avg, Count: count}
+	}
+	results := []*RatedRestaurant{rated("c", 4, 2), rated("b", 4, 2), rated("a", 3, 9), rated("d", 4, 5)}
+	slices.SortFunc(results, CompareRated)
+
+	var ids []string
+	for _, r := range results {
+		ids = append(ids, r.Restaurant.ID)
+	}
+	assert.Equal(t, []string{"d", "b", "c", "a"}, ids)
+	assert.Equal(t, DefaultPageLimit, NormalizeLimit(0))
+	assert.Equal(t, MaxPageLimit, NormalizeLimit(MaxPageLimit+1))
+}
@@ -2,10 +2,18 @@
 package domain
 
 type AddressBSON struct {
-	Street string `bson:"street"`
-	City   string `bson:"city"`
-	State  string `bson:"state"`
-	Zip    string `bson:"zip"`
+	Street   string        `bson:"street"`
+	City     string        `bson:"city"`
+	State    string        `bson:"state"`
+	Zip      string        `bson:"zip"`
+	Location *GeoJSONPoint `bson:"location,omitempty"`
+}
+
+// GeoJSONPoint is the GeoJSON form Mongo indexes with 2dsphere. Coordinates are
+// [longitude, latitude].
+type GeoJSONPoint struct {
+	Type        string    `bson:"type"`
+	Coordinates []float64 `bson:"coordinates"`
 }
 
 type MenuItemBSON struct {
@@ -6,7 +6,7 @@ func ConvertRestaurantToJSON(dto Restaurant) RestaurantJSON {
 		Name:      dto.Name,
 		Email:     dto.Email,
 		Age:       dto.Age,
-		Address:   AddressJSON(dto.Address),
+		Address:   convertAddressToJSON(dto.Address),
 		Owners:    dto.Owners,
 		Employees: convertEmployeesToJSON(dto.Employees),
 		Menu:      ConvertMenuItemsToJSON(dto.Menu),
@@ -38,13 +38,51 @@ func convertRatingsToJSON(src []Rating) []RatingJSON {
 	return out
 }
 
+func (src *RestaurantJSON) RestaurantFromJSONToDTO() *Restaurant {
+	return &Restaurant{
+		ID:        src.ID,
+		Name:      src.Name,
+		Email:     src.Email,
+		Age:       src.Age,
+		Address:   convertAddressFromJSON(src.Address),
+		Owners:    src.Owners,
+		Employees: convertEmployeesFromJSON(src.Employees),
+		Menu:      ConvertMenuItemsFromJSON(src.Menu),
+		Ratings:   convertRatingsFromJSON(src.Ratings),
+	}
+}
+
+func convertEmployeesFromJSON(src []EmployeeJSON) []Employee {
+	out := make([]Employee, len(src))
+	for i, e := range src {
+		out[i] = Employee(e)
+	}
+	return out
+}
+
+func ConvertMenuItemsFromJSON(src []MenuItemJSON) []MenuItem {
+	out := make([]MenuItem, len(src))
+	for i, m := range src {
+		out[i] = MenuItem(m)
+	}
+	return out
+}
+
+func convertRatingsFromJSON(src []RatingJSON) []Rating {
+	out := make([]Rating, len(src))
+	for i, r := range src {
+		out[i] = Rating(r)
+	}
+	return out
+}
+
 func RestaurantFromDTOToBSON(dto Restaurant) RestaurantBSON {
 	return RestaurantBSON{
 		ID:        dto.ID,
 		Name:      dto.Name,
 		Email:     dto.Email,
 		Age:       dto.Age,
-		Address:   AddressBSON(dto.Address),
+		Address:   convertAddressToBSON(dto.Address),
 		Owners:    dto.Owners,
 		Employees: convertEmployeesToBSON(dto.Employees),
 		Menu:      ConvertMenuItemsToBSON(dto.Menu),
@@ -82,7 +120,7 @@ func (src *RestaurantBSON) RestaurantFromBSONToDTO() *Restaurant {
 		Name:      src.Name,
 		Email:     src.Email,
 		Age:       src.Age,
-		Address:   Address(src.Address),
+		Address:   convertAddressToDTO(src.Address),
 		Owners:    src.Owners,
 		Employees: convertEmployeesToDTO(src.Employees),
 		Menu:      ConvertMenuItemsToDTO(src.Menu),
@@ -113,3 +151,40 @@ func convertRatingsToDTO(ratingBSON []RatingBSON) []Rating {
 	}
 	return out
 }
+
+func convertAddressToJSON(src Address) AddressJSON {
+	dst := AddressJSON{Street: src.Street, City: src.City, State: src.State, Zip: src.Zip}
+	if src.Location != nil {
+		dst.Location = &GeoPointJSON{Lat: src.Location.Lat, Lng: src.Location.Lng}
+	}
+	return dst
+}
+
+func convertAddressFromJSON(src AddressJSON) Address {
+	dst := Address{Street: src.Street, City: src.City, State: src.State, Zip: src.Zip}
+	if src.Location != nil {
+		dst.Location = &GeoPoint{Lat: src.Location.Lat, Lng: src.Location.Lng}
+	}
+	return dst
+}
+
+func convertAddressToBSON(src Address) AddressBSON {
+	dst := AddressBSON{Street: src.Street, City: src.City, State: src.State, Zip: src.Zip}
+	if src.Location != nil {
+		dst.Location = GeoJSONPointFrom(*src.Location)
+	}
+	return dst
+}
+
+func convertAddressToDTO(src AddressBSON) Address {
+	dst := Address{Street: src.Street, City: src.City, State: src.State, Zip: src.Zip}
+	if src.Location != nil && len(src.Location.Coordinates) == 2 {
+		dst.Location = &GeoPoint{Lat: src.Location.Coordinates[1], Lng: src.Location.Coordinates[0]}
+	}
+	return dst
+}
+
+// GeoJSONPointFrom converts p to a GeoJSON point.
+func GeoJSONPointFrom(p GeoPoint) *GeoJSONPoint {
+	return &GeoJSONPoint{Type: "Point", Coordinates: []float64{p.Lng, p.Lat}}
+}
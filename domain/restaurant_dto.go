@@ -1,11 +1,39 @@
 // Package domain defines the data structures and interfaces for the restaurant domain.
 package domain
 
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrEmptyCriteria = errors.New("search criteria are empty")
+)
+
 type Address struct {
 	Street string
 	City   string
 	State  string
 	Zip    string
+	// Location is nil when the address has not been geocoded.
+	Location *GeoPoint
+}
+
+// IsZero reports whether no address field is set. Location is not a search criterion
+// and is ignored, as it is by Matches.
+func (a Address) IsZero() bool {
+	return a.Street == "" && a.City == "" && a.State == "" && a.Zip == ""
+}
+
+// Matches reports whether addr satisfies a used as search criteria: empty fields match
+// anything, Street, City and State match case-insensitively and Zip is a prefix.
+func (a Address) Matches(addr Address) bool {
+	return (a.Street == "" || strings.EqualFold(a.Street, addr.Street)) &&
+		(a.City == "" || strings.EqualFold(a.City, addr.City)) &&
+		(a.State == "" || strings.EqualFold(a.State, addr.State)) &&
+		strings.HasPrefix(addr.Zip, a.Zip)
 }
 
 type MenuItem struct {
@@ -37,3 +65,27 @@ type Restaurant struct {
 	Menu      []MenuItem
 	Ratings   []Rating
 }
+
+// Clone returns a deep copy of the restaurant.
+func (r *Restaurant) Clone() *Restaurant {
+	if r == nil {
+		return nil
+	}
+	c := *r
+	if r.Address.Location != nil {
+		loc := *r.Address.Location
+		c.Address.Location = &loc
+	}
+	c.Owners = cloneSlice(r.Owners)
+	c.Employees = cloneSlice(r.Employees)
+	c.Menu = cloneSlice(r.Menu)
+	c.Ratings = cloneSlice(r.Ratings)
+	return &c
+}
+
+func cloneSlice[T any](src []T) []T {
+	if src == nil {
+		return nil
+	}
+	return append(make([]T, 0, len(src)), src...)
+}
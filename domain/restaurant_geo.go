@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrInvalidPoint  = errors.New("invalid geo point")
+	ErrInvalidRadius = errors.New("invalid search radius")
+)
+
+// earthRadiusMeters is the radius MongoDB uses for 2dsphere distances, so distances
+// computed here agree with $geoNear.
+const earthRadiusMeters = 6378100
+
+// GeoPoint is a WGS84 coordinate in degrees.
+type GeoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+func (p GeoPoint) Validate() error {
+	if math.IsNaN(p.Lat) || math.IsNaN(p.Lng) || p.Lat < -90 || p.Lat > 90 || p.Lng < -180 || p.Lng > 180 {
+		return fmt.Errorf("%w: lat %v, lng %v", ErrInvalidPoint, p.Lat, p.Lng)
+	}
+	return nil
+}
+
+// ValidateNear checks the arguments of FindNear: point must be valid and radiusMeters a
+// finite distance of at least 0.
+func ValidateNear(point GeoPoint, radiusMeters float64) error {
+	if err := point.Validate(); err != nil {
+		return err
+	}
+	if !(radiusMeters >= 0) || math.IsInf(radiusMeters, 1) {
+		return fmt.Errorf("%w: %v meters", ErrInvalidRadius, radiusMeters)
+	}
+	return nil
+}
+
+// HaversineMeters returns the great-circle distance between a and b on a sphere of
+// earthRadiusMeters, the radius Mongo uses for 2dsphere queries.
+func HaversineMeters(a, b GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// RestaurantDistance is a FindNear result.
+type RestaurantDistance struct {
+	Restaurant     *Restaurant
+	DistanceMeters float64
+}
+
+// RestaurantGeoReader finds restaurants by location. Restaurants without a location
+// are never returned.
+type RestaurantGeoReader interface {
+	// FindNear returns the restaurants within radiusMeters of point, nearest first. It
+	// fails as ValidateNear does on invalid arguments.
+	FindNear(ctx context.Context, point GeoPoint, radiusMeters float64) ([]*RestaurantDistance, error)
+}
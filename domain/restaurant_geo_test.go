@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHaversineMeters(t *testing.T) {
+	paris := GeoPoint{Lat: 48.8566, Lng: 2.3522}
+	london := GeoPoint{Lat: 51.5074, Lng: -0.1278}
+
+	assert.InDelta(t, 343_500, HaversineMeters(paris, london), 500)
+	assert.InDelta(t, HaversineMeters(paris, london), HaversineMeters(london, paris), 1e-6)
+	assert.Zero(t, HaversineMeters(paris, paris))
+	assert.InDelta(t, 20_037_392, HaversineMeters(GeoPoint{Lat: 0, Lng: 0}, GeoPoint{Lat: 0, Lng: 180}), 1)
+}
+
+func TestAddressLocationRoundTrip(t *testing.T) {
+	r := &Restaurant{ID: "r1", Address: Address{City: "Paris", Location: &GeoPoint{Lat: 48.8566, Lng: 2.3522}}}
+
+	bsonDoc := RestaurantFromDTOToBSON(*r)
+	assert.Equal(t, &GeoJSONPoint{Type: "Point", Coordinates: []float64{2.3522, 48.8566}}, bsonDoc.Address.Location)
+	assert.Equal(t, r.Address, bsonDoc.RestaurantFromBSONToDTO().Address)
+	jsonDoc := ConvertRestaurantToJSON(*r)
+	assert.Equal(t, r.Address, jsonDoc.RestaurantFromJSONToDTO().Address)
+
+	c := r.Clone()
+	c.Address.Location.Lat = 0
+	assert.Equal(t, 48.8566, r.Address.Location.Lat)
+}
+
+func TestValidateNear(t *testing.T) {
+	paris := GeoPoint{Lat: 48.8566, Lng: 2.3522}
+	assert.NoError(t, ValidateNear(paris, 5000))
+	assert.NoError(t, ValidateNear(paris, 0))
+	assert.ErrorIs(t, ValidateNear(GeoPoint{Lat: math.NaN()}, 5000), ErrInvalidPoint)
+	for _, radius := range []float64{-1, math.NaN(), math.Inf(1), math.Inf(-1)} {
+		assert.ErrorIs(t, ValidateNear(paris, radius), ErrInvalidRadius, "radius %v", radius)
+	}
+}
@@ -2,10 +2,16 @@
 package domain
 
 type AddressJSON struct {
-	Street string `json:"street"`
-	City   string `json:"city"`
-	State  string `json:"state"`
-	Zip    string `json:"zip"`
+	Street   string        `json:"street"`
+	City     string        `json:"city"`
+	State    string        `json:"state"`
+	Zip      string        `json:"zip"`
+	Location *GeoPointJSON `json:"location,omitempty"`
+}
+
+type GeoPointJSON struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
 }
 
 type MenuItemJSON struct {
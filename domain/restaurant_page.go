@@ -0,0 +1,115 @@
+package domain
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+// SortField is a restaurant field a page can be ordered by. Ties are broken by ID.
+type SortField string
+
+const (
+	SortByID   SortField = "ID"
+	SortByName SortField = "Name"
+	SortByAge  SortField = "Age"
+)
+
+// PageRequest asks for one page of results. Cursor is the NextCursor of the previous
+// page and must be used with the same SortBy and Descending values.
+type PageRequest struct {
+	Limit      int
+	Cursor     string
+	SortBy     SortField
+	Descending bool
+}
+
+// Normalize applies the default limit and sort field and caps the limit at MaxPageLimit.
+func (p PageRequest) Normalize() PageRequest {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	if p.SortBy == "" {
+		p.SortBy = SortByID
+	}
+	return p
+}
+
+// RestaurantPage is one page of restaurants. NextCursor is empty on the last page.
+type RestaurantPage struct {
+	Items      []*Restaurant
+	NextCursor string
+	HasMore    bool
+	TotalCount int64
+}
+
+// PageCursor is the decoded form of a keyset pagination token: the sort key and ID
+// of the last restaurant of the previous page.
+type PageCursor struct {
+	SortBy     SortField `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	ID         string    `json:"id"`
+	Name       string    `json:"n,omitempty"`
+	Age        int       `json:"a,omitempty"`
+}
+
+// Value returns the sort key recorded in the cursor.
+func (c PageCursor) Value() any {
+	switch c.SortBy {
+	case SortByName:
+		return c.Name
+	case SortByAge:
+		return c.Age
+	default:
+		return c.ID
+	}
+}
+
+// EncodeCursor returns the token pointing after last for the given page request.
+func EncodeCursor(page PageRequest, last *Restaurant) string {
+	c := PageCursor{SortBy: page.SortBy, Descending: page.Descending, ID: last.ID}
+	switch page.SortBy {
+	case SortByName:
+		c.Name = last.Name
+	case SortByAge:
+		c.Age = last.Age
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses the cursor of page, checking it was issued for the same ordering
+// and points at a restaurant.
+func DecodeCursor(page PageRequest) (PageCursor, error) {
+	var c PageCursor
+	raw, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	if c.SortBy != page.SortBy || c.Descending != page.Descending || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// RestaurantPagedReader defines paginated variants of the RestaurantReader finders.
+type RestaurantPagedReader interface {
+	FindByNamePaged(ctx context.Context, name string, page PageRequest) (*RestaurantPage, error)
+	FindByOwnerPaged(ctx context.Context, owner string, page PageRequest) (*RestaurantPage, error)
+	FindByRatingPaged(ctx context.Context, score int, page PageRequest) (*RestaurantPage, error)
+	FindByMenuItemPaged(ctx context.Context, itemName string, page PageRequest) (*RestaurantPage, error)
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	last := &Restaurant{ID: "r7", Name: "Soup Kitchen", Age: 12}
+	tests := []struct {
+		page PageRequest
+		want PageCursor
+	}{
+		{PageRequest{SortBy: SortByID}, PageCursor{SortBy: SortByID, ID: "r7"}},
+		{PageRequest{SortBy: SortByName}, PageCursor{SortBy: SortByName, ID: "r7", Name: "Soup Kitchen"}},
+		{PageRequest{SortBy: SortByAge, Descending: true}, PageCursor{SortBy: SortByAge, Descending: true, ID: "r7", Age: 12}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.page.SortBy), func(t *testing.T) {
+			tt.page.Cursor = EncodeCursor(tt.page, last)
+			got, err := DecodeCursor(tt.page)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, got.Value(), map[SortField]any{SortByID: "r7", SortByName: "Soup Kitchen", SortByAge: 12}[tt.page.SortBy])
+		})
+	}
+}
+
+func TestDecodeCursorRejectsTampering(t *testing.T) {
+	page := PageRequest{SortBy: SortByName}
+	valid := EncodeCursor(page, &Restaurant{ID: "r7", Name: "Soup Kitchen"})
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+
+	tests := []struct {
+		name   string
+		cursor string
+		page   PageRequest
+	}{
+		{"not base64", "%%%", page},
+		{"truncated", valid[:len(valid)/2], page},
+		{"not json", encode("soup"), page},
+		{"other sort field", valid, PageRequest{SortBy: SortByAge}},
+		{"other direction", valid, PageRequest{SortBy: SortByName, Descending: true}},
+		{"edited sort field", encode(`{"s":"Age","id":"r7"}`), page},
+		{"missing id", encode(`{"s":"Name","n":"Soup Kitchen"}`), page},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.page.Cursor = tt.cursor
+			_, err := DecodeCursor(tt.page)
+			assert.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
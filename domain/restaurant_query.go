@@ -0,0 +1,368 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrInvalidQuery = errors.New("invalid restaurant query")
+
+// QueryField identifies a restaurant field by its domain path. Fields below an array
+// (e.g. Menu.Price) match when any element matches, use ElemMatch to require a
+// single element to satisfy several predicates.
+type QueryField string
+
+const (
+	FieldID    QueryField = "ID"
+	FieldName  QueryField = "Name"
+	FieldEmail QueryField = "Email"
+	FieldAge   QueryField = "Age"
+
+	FieldStreet QueryField = "Address.Street"
+	FieldCity   QueryField = "Address.City"
+	FieldState  QueryField = "Address.State"
+	FieldZip    QueryField = "Address.Zip"
+
+	FieldOwners QueryField = "Owners"
+
+	FieldEmployees    QueryField = "Employees"
+	FieldEmployeeName QueryField = "Employees.Name"
+	FieldEmployeeRole QueryField = "Employees.Role"
+	FieldEmployeeAge  QueryField = "Employees.Age"
+
+	FieldMenu                QueryField = "Menu"
+	FieldMenuItemName        QueryField = "Menu.Name"
+	FieldMenuItemDescription QueryField = "Menu.Description"
+	FieldMenuItemPrice       QueryField = "Menu.Price"
+
+	FieldRatings     QueryField = "Ratings"
+	FieldRatingScore QueryField = "Ratings.Score"
+	FieldRatingUser  QueryField = "Ratings.User"
+	FieldRatingNote  QueryField = "Ratings.Note"
+)
+
+// arrayFields are the fields ElemMatch can be applied to.
+var arrayFields = map[QueryField]bool{
+	FieldEmployees: true,
+	FieldMenu:      true,
+	FieldRatings:   true,
+}
+
+type QueryOp string
+
+const (
+	OpEq        QueryOp = "eq"
+	OpNe        QueryOp = "ne"
+	OpGt        QueryOp = "gt"
+	OpGte       QueryOp = "gte"
+	OpLt        QueryOp = "lt"
+	OpLte       QueryOp = "lte"
+	OpIn        QueryOp = "in"
+	OpContains  QueryOp = "contains"
+	OpAnd       QueryOp = "and"
+	OpOr        QueryOp = "or"
+	OpNot       QueryOp = "not"
+	OpElemMatch QueryOp = "elem_match"
+)
+
+// RestaurantQuery is a backend-agnostic restaurant filter. Build it with the
+// constructors below, e.g.
+//
+//	And(ElemMatch(FieldMenu, Eq(FieldMenuItemName, "Pho")), Eq(FieldCity, "Austin"), Gte(FieldRatingScore, 4))
+type RestaurantQuery struct {
+	Op       QueryOp
+	Field    QueryField
+	Value    any
+	Values   []any
+	Children []RestaurantQuery
+}
+
+func Eq(field QueryField, value any) RestaurantQuery {
+	return RestaurantQuery{Op: OpEq, Field: field, Value: value}
+}
+func Ne(field QueryField, value any) RestaurantQuery {
+	return RestaurantQuery{Op: OpNe, Field: field, Value: value}
+}
+func Gt(field QueryField, value any) RestaurantQuery {
+	return RestaurantQuery{Op: OpGt, Field: field, Value: value}
+}
+func Gte(field QueryField, value any) RestaurantQuery {
+	return RestaurantQuery{Op: OpGte, Field: field, Value: value}
+}
+func Lt(field QueryField, value any) RestaurantQuery {
+	return RestaurantQuery{Op: OpLt, Field: field, Value: value}
+}
+func Lte(field QueryField, value any) RestaurantQuery {
+	return RestaurantQuery{Op: OpLte, Field: field, Value: value}
+}
+
+// Between matches values in the inclusive range [low, high].
+func Between(field QueryField, low, high any) RestaurantQuery {
+	return And(Gte(field, low), Lte(field, high))
+}
+
+func In(field QueryField, values ...any) RestaurantQuery {
+	return RestaurantQuery{Op: OpIn, Field: field, Values: values}
+}
+
+// Contains matches string fields containing text, ignoring case.
+func Contains(field QueryField, text string) RestaurantQuery {
+	return RestaurantQuery{Op: OpContains, Field: field, Value: text}
+}
+
+func And(queries ...RestaurantQuery) RestaurantQuery {
+	return RestaurantQuery{Op: OpAnd, Children: queries}
+}
+func Or(queries ...RestaurantQuery) RestaurantQuery {
+	return RestaurantQuery{Op: OpOr, Children: queries}
+}
+func Not(query RestaurantQuery) RestaurantQuery {
+	return RestaurantQuery{Op: OpNot, Children: []RestaurantQuery{query}}
+}
+
+// ElemMatch matches when a single element of the array field satisfies every query.
+// The queries use the full field paths, e.g. ElemMatch(FieldMenu, Lt(FieldMenuItemPrice, 10)).
+func ElemMatch(array QueryField, queries ...RestaurantQuery) RestaurantQuery {
+	return RestaurantQuery{Op: OpElemMatch, Field: array, Children: queries}
+}
+
+// Validate checks the query only references known fields and is well formed.
+func (q RestaurantQuery) Validate() error {
+	return q.validate("")
+}
+
+// validate checks q inside scope, the array field of an enclosing ElemMatch: every
+// field q references, however deeply nested in And, Or and Not, must belong to it.
+func (q RestaurantQuery) validate(scope QueryField) error {
+	if scope != "" && q.Field != "" && q.Field != scope && !strings.HasPrefix(string(q.Field), string(scope)+".") {
+		return fmt.Errorf("%w: %s is not a field of %s", ErrInvalidQuery, q.Field, scope)
+	}
+	switch q.Op {
+	case OpAnd, OpOr:
+		if len(q.Children) == 0 {
+			return fmt.Errorf("%w: %s without queries", ErrInvalidQuery, q.Op)
+		}
+	case OpNot:
+		if len(q.Children) != 1 {
+			return fmt.Errorf("%w: not takes exactly one query", ErrInvalidQuery)
+		}
+	case OpElemMatch:
+		if !arrayFields[q.Field] {
+			return fmt.Errorf("%w: %s is not an array field", ErrInvalidQuery, q.Field)
+		}
+		scope = q.Field
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte, OpIn, OpContains:
+		if _, ok := fieldValues(&Restaurant{}, q.Field); !ok {
+			return fmt.Errorf("%w: unknown field %q", ErrInvalidQuery, q.Field)
+		}
+		if q.Op == OpContains {
+			if _, ok := q.Value.(string); !ok {
+				return fmt.Errorf("%w: contains needs a string", ErrInvalidQuery)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown operator %q", ErrInvalidQuery, q.Op)
+	}
+	for _, c := range q.Children {
+		if err := c.validate(scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches evaluates the query in memory with the same semantics as the Mongo
+// translation: comparisons on array fields hold when any element matches.
+func (q RestaurantQuery) Matches(r *Restaurant) bool {
+	switch q.Op {
+	case OpAnd:
+		for _, c := range q.Children {
+			if !c.Matches(r) {
+				return false
+			}
+		}
+		return true
+	case OpOr:
+		for _, c := range q.Children {
+			if c.Matches(r) {
+				return true
+			}
+		}
+		return false
+	case OpNot:
+		return len(q.Children) == 1 && !q.Children[0].Matches(r)
+	case OpElemMatch:
+		for _, elem := range elements(r, q.Field) {
+			if And(q.Children...).Matches(elem) {
+				return true
+			}
+		}
+		return false
+	case OpNe:
+		return !Eq(q.Field, q.Value).Matches(r)
+	}
+
+	values, _ := fieldValues(r, q.Field)
+	for _, v := range values {
+		if matchValue(q, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchValue(q RestaurantQuery, v any) bool {
+	switch q.Op {
+	case OpIn:
+		for _, candidate := range q.Values {
+			if c, ok := compareValues(v, candidate); ok && c == 0 {
+				return true
+			}
+		}
+		return false
+	case OpContains:
+		s, ok := v.(string)
+		text, _ := q.Value.(string)
+		return ok && strings.Contains(strings.ToLower(s), strings.ToLower(text))
+	}
+
+	c, ok := compareValues(v, q.Value)
+	if !ok {
+		return false
+	}
+	switch q.Op {
+	case OpEq:
+		return c == 0
+	case OpGt:
+		return c > 0
+	case OpGte:
+		return c >= 0
+	case OpLt:
+		return c < 0
+	case OpLte:
+		return c <= 0
+	}
+	return false
+}
+
+// compareValues compares numbers with numbers and strings with strings.
+func compareValues(a, b any) (int, bool) {
+	if as, ok := a.(string); ok {
+		bs, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(as, bs), true
+	}
+	af, ok := toFloat(a)
+	if !ok {
+		return 0, false
+	}
+	bf, ok := toFloat(b)
+	if !ok {
+		return 0, false
+	}
+	switch {
+	case af < bf:
+		return -1, true
+	case af > bf:
+		return 1, true
+	}
+	return 0, true
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// elements returns one restaurant per element of the array field, holding only that
+// element, so ElemMatch children can be evaluated with Matches.
+func elements(r *Restaurant, array QueryField) []*Restaurant {
+	var out []*Restaurant
+	switch array {
+	case FieldEmployees:
+		for _, e := range r.Employees {
+			out = append(out, &Restaurant{Employees: []Employee{e}})
+		}
+	case FieldMenu:
+		for _, m := range r.Menu {
+			out = append(out, &Restaurant{Menu: []MenuItem{m}})
+		}
+	case FieldRatings:
+		for _, rt := range r.Ratings {
+			out = append(out, &Restaurant{Ratings: []Rating{rt}})
+		}
+	}
+	return out
+}
+
+// fieldValues returns the values of field on r; array paths return one value per element.
+func fieldValues(r *Restaurant, field QueryField) ([]any, bool) {
+	switch field {
+	case FieldID:
+		return []any{r.ID}, true
+	case FieldName:
+		return []any{r.Name}, true
+	case FieldEmail:
+		return []any{r.Email}, true
+	case FieldAge:
+		return []any{r.Age}, true
+	case FieldStreet:
+		return []any{r.Address.Street}, true
+	case FieldCity:
+		return []any{r.Address.City}, true
+	case FieldState:
+		return []any{r.Address.State}, true
+	case FieldZip:
+		return []any{r.Address.Zip}, true
+	case FieldOwners:
+		return collect(r.Owners, func(o string) any { return o }), true
+	case FieldEmployeeName:
+		return collect(r.Employees, func(e Employee) any { return e.Name }), true
+	case FieldEmployeeRole:
+		return collect(r.Employees, func(e Employee) any { return e.Role }), true
+	case FieldEmployeeAge:
+		return collect(r.Employees, func(e Employee) any { return e.Age }), true
+	case FieldMenuItemName:
+		return collect(r.Menu, func(m MenuItem) any { return m.Name }), true
+	case FieldMenuItemDescription:
+		return collect(r.Menu, func(m MenuItem) any { return m.Description }), true
+	case FieldMenuItemPrice:
+		return collect(r.Menu, func(m MenuItem) any { return m.Price }), true
+	case FieldRatingScore:
+		return collect(r.Ratings, func(rt Rating) any { return rt.Score }), true
+	case FieldRatingUser:
+		return collect(r.Ratings, func(rt Rating) any { return rt.User }), true
+	case FieldRatingNote:
+		return collect(r.Ratings, func(rt Rating) any { return rt.Note }), true
+	}
+	return nil, false
+}
+
+func collect[T any](src []T, fn func(T) any) []any {
+	out := make([]any, len(src))
+	for i, v := range src {
+		out[i] = fn(v)
+	}
+	return out
+}
+
+// RestaurantQuerier finds restaurants matching a RestaurantQuery.
+type RestaurantQuerier interface {
+	Find(ctx context.Context, q RestaurantQuery) ([]*Restaurant, error)
+}
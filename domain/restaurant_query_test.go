@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestaurantQueryMatches(t *testing.T) {
+	r := &Restaurant{
+		Name:    "Noodle Bar",
+		Address: Address{City: "Austin"},
+		Owners:  []string{"Ann"},
+		Menu: []MenuItem{
+			{Name: "Pho", Price: 14},
+			{Name: "Tea", Price: 3},
+		},
+		Ratings: []Rating{{Score: 5}, {Score: 2}},
+	}
+
+	cases := []struct {
+		name  string
+		query RestaurantQuery
+		want  bool
+	}{
+		{"eq", Eq(FieldCity, "Austin"), true},
+		{"combined", And(Eq(FieldMenuItemName, "Pho"), Eq(FieldCity, "Austin"), Gte(FieldRatingScore, 4)), true},
+		{"any element", Lt(FieldMenuItemPrice, 5), true},
+		{"elem match same element", ElemMatch(FieldMenu, Eq(FieldMenuItemName, "Pho"), Lt(FieldMenuItemPrice, 5)), false},
+		{"elem match or", ElemMatch(FieldMenu, Or(Eq(FieldMenuItemName, "Tea"), Gt(FieldMenuItemPrice, 20)), Lt(FieldMenuItemPrice, 5)), true},
+		{"between", Between(FieldMenuItemPrice, 10, 20), true},
+		{"contains ignores case", Contains(FieldName, "noodle"), true},
+		{"in", In(FieldOwners, "Bob", "Ann"), true},
+		{"ne on array", Ne(FieldRatingScore, 2), false},
+		{"or", Or(Eq(FieldCity, "Dallas"), Eq(FieldName, "Noodle Bar")), true},
+		{"not", Not(Eq(FieldCity, "Austin")), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, tc.query.Validate())
+			assert.Equal(t, tc.want, tc.query.Matches(r))
+		})
+	}
+}
+
+func TestRestaurantQueryValidate(t *testing.T) {
+	assert.ErrorIs(t, Eq("Menu.Calories", 1).Validate(), ErrInvalidQuery)
+	assert.ErrorIs(t, ElemMatch(FieldName, Eq(FieldName, "x")).Validate(), ErrInvalidQuery)
+	assert.ErrorIs(t, ElemMatch(FieldMenu, Eq(FieldRatingScore, 1)).Validate(), ErrInvalidQuery)
+	assert.ErrorIs(t, ElemMatch(FieldMenu, Or(Eq(FieldMenuItemName, "Pho"), Eq(FieldRatingScore, 1))).Validate(), ErrInvalidQuery)
+	assert.ErrorIs(t, ElemMatch(FieldMenu, And(Not(Eq(FieldCity, "Austin")))).Validate(), ErrInvalidQuery)
+	assert.NoError(t, ElemMatch(FieldMenu, Or(Eq(FieldMenuItemName, "Pho"), Not(Lt(FieldMenuItemPrice, 5)))).Validate())
+	assert.ErrorIs(t, And().Validate(), ErrInvalidQuery)
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidTextSearch = errors.New("invalid text search")
+
+// Relevance weights of the searched fields: a name match outranks a menu item name
+// match, which outranks a description match.
+const (
+	TextWeightName                = 10
+	TextWeightMenuItemName        = 5
+	TextWeightMenuItemDescription = 1
+)
+
+// TextQuery searches restaurant names and menu item names and descriptions.
+type TextQuery struct {
+	// Search uses the Mongo $text syntax: words match any of them, "quoted phrases"
+	// must all appear and a leading - excludes a word or phrase.
+	Search string
+	// Language selects stemming and stop words and must be one of TextLanguages.
+	// "none" matches words as they are. Empty means english.
+	Language string
+	// Limit defaults to DefaultPageLimit and is capped at MaxPageLimit.
+	Limit int
+}
+
+// Normalize applies the limit defaults and the default language.
+func (q TextQuery) Normalize() TextQuery {
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageLimit
+	}
+	q.Limit = min(q.Limit, MaxPageLimit)
+	if q.Language == "" {
+		q.Language = "english"
+	}
+	return q
+}
+
+// TextLanguages are the languages of the Mongo text index, by name and ISO code.
+// Repositories may support fewer and reject the others with ErrInvalidTextSearch.
+var TextLanguages = map[string]bool{
+	"none": true, "danish": true, "da": true, "dutch": true, "nl": true,
+	"english": true, "en": true, "finnish": true, "fi": true, "french": true, "fr": true,
+	"german": true, "de": true, "hungarian": true, "hu": true, "italian": true, "it": true,
+	"norwegian": true, "nb": true, "portuguese": true, "pt": true, "romanian": true, "ro": true,
+	"russian": true, "ru": true, "spanish": true, "es": true, "swedish": true, "sv": true,
+	"turkish": true, "tr": true,
+}
+
+// Parse checks the language of a normalized q and parses its search.
+func (q TextQuery) Parse() (TextSearch, error) {
+	if !TextLanguages[q.Language] {
+		return TextSearch{}, fmt.Errorf("%w: unknown language %q", ErrInvalidTextSearch, q.Language)
+	}
+	return ParseTextSearch(q.Search)
+}
+
+// RestaurantTextMatch is a SearchText result. Scores rank results of one search and
+// are not comparable across searches or repositories.
+type RestaurantTextMatch struct {
+	Restaurant *Restaurant
+	Score      float64
+}
+
+type RestaurantTextSearcher interface {
+	// SearchText returns the matching restaurants, most relevant first.
+	SearchText(ctx context.Context, q TextQuery) ([]*RestaurantTextMatch, error)
+}
+
+// TextSearch is a parsed TextQuery.Search.
+type TextSearch struct {
+	Terms          []string
+	Phrases        []string
+	NegatedTerms   []string
+	NegatedPhrases []string
+}
+
+// ParseTextSearch splits search into terms, phrases and their negations. It fails with
+// ErrInvalidTextSearch on unbalanced quotes or when nothing is searched for but
+// exclusions, which can never match.
+func ParseTextSearch(search string) (TextSearch, error) {
+	var ts TextSearch
+	rest := strings.TrimSpace(search)
+	for rest != "" {
+		negated := strings.HasPrefix(rest, "-")
+		token := strings.TrimPrefix(rest, "-")
+		if strings.HasPrefix(token, `"`) {
+			phrase, after, ok := strings.Cut(token[1:], `"`)
+			if !ok {
+				return TextSearch{}, fmt.Errorf("%w: unbalanced quote in %q", ErrInvalidTextSearch, search)
+			}
+			if phrase = strings.TrimSpace(phrase); phrase != "" {
+				if negated {
+					ts.NegatedPhrases = append(ts.NegatedPhrases, phrase)
+				} else {
+					ts.Phrases = append(ts.Phrases, phrase)
+				}
+			}
+			rest = strings.TrimSpace(after)
+			continue
+		}
+		word, after := token, ""
+		if i := strings.IndexFunc(token, unicode.IsSpace); i >= 0 {
+			word, after = token[:i], token[i:]
+		}
+		if word != "" {
+			if negated {
+				ts.NegatedTerms = append(ts.NegatedTerms, word)
+			} else {
+				ts.Terms = append(ts.Terms, word)
+			}
+		}
+		rest = strings.TrimSpace(after)
+	}
+	if len(ts.Terms) == 0 && len(ts.Phrases) == 0 {
+		return TextSearch{}, fmt.Errorf("%w: %q has nothing to search for", ErrInvalidTextSearch, search)
+	}
+	return ts, nil
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTextSearch(t *testing.T) {
+	ts, err := ParseTextSearch(` pizza  "wood fired"	-anchovy -"deep dish" basil `)
+	require.NoError(t, err)
+	assert.Equal(t, TextSearch{
+		Terms:          []string{"pizza", "basil"},
+		Phrases:        []string{"wood fired"},
+		NegatedTerms:   []string{"anchovy"},
+		NegatedPhrases: []string{"deep dish"},
+	}, ts)
+
+	for _, bad := range []string{"", "   ", "-pizza", `-"deep dish"`, `"wood fired`} {
+		_, err := ParseTextSearch(bad)
+		assert.ErrorIs(t, err, ErrInvalidTextSearch, bad)
+	}
+}
+
+func TestTextQueryNormalize(t *testing.T) {
+	q := TextQuery{Search: "pizza"}.Normalize()
+	assert.Equal(t, DefaultPageLimit, q.Limit)
+	assert.Equal(t, "english", q.Language)
+	assert.Equal(t, MaxPageLimit, TextQuery{Limit: MaxPageLimit + 1}.Normalize().Limit)
+}
+
+func TestTextQueryParse(t *testing.T) {
+	ts, err := TextQuery{Search: "pizza", Language: "fr"}.Normalize().Parse()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pizza"}, ts.Terms)
+
+	_, err = TextQuery{Search: "pizza", Language: "klingon"}.Normalize().Parse()
+	assert.ErrorIs(t, err, ErrInvalidTextSearch)
+	_, err = TextQuery{Search: "-pizza"}.Normalize().Parse()
+	assert.ErrorIs(t, err, ErrInvalidTextSearch)
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"iter"
 )
 
 //This will not exists in the actual repo package. It will be defined per application.
@@ -11,13 +12,23 @@ import (
 // RestaurantReader defines the interface for reading restaurants, This is a interface
 // that is only using domain methods. It does not specify which database or how the data is stored.
 type RestaurantReader interface {
-	FindByAddress(ctx context.Context, address string) ([]*Restaurant, error)
+	// FindByAddress searches with the set fields of address, see Address.Matches. It
+	// fails with ErrEmptyCriteria when no field is set.
+	FindByAddress(ctx context.Context, address Address) ([]*Restaurant, error)
 	FindByName(ctx context.Context, name string) ([]*Restaurant, error)
 	FindByOwner(ctx context.Context, owner string) ([]*Restaurant, error)
 	FindByRating(ctx context.Context, score int) ([]*Restaurant, error)
 	FindByMenuItem(ctx context.Context, itemName string) ([]*Restaurant, error)
 }
 
+// RestaurantStreamer defines streaming reads for bulk jobs. Restaurants are yielded one
+// at a time as they are read from the store instead of being loaded into a slice; an
+// error is yielded once and ends the sequence.
+type RestaurantStreamer interface {
+	StreamAll(ctx context.Context) iter.Seq2[*Restaurant, error]
+	StreamByName(ctx context.Context, name string) iter.Seq2[*Restaurant, error]
+}
+
 // RestaurantWriter defines the interface for writing restaurants, This is a interface
 // that is only using domain methods. It does not specify which database or how the data is stored.
 type RestaurantWriter interface {
@@ -0,0 +1,12 @@
+package domain
+
+import "context"
+
+// UnitOfWork groups repository writes so they succeed or fail together.
+type UnitOfWork interface {
+	// WithTransaction runs fn in a transaction. Repository calls made with the context
+	// passed to fn take part in it. The transaction commits when fn returns nil and is
+	// rolled back otherwise. fn may be run again on transient errors, so it must not
+	// have side effects outside the repositories. Nested calls join the outer transaction.
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -2,14 +2,17 @@ package infra
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 )
 
 var (
-	DURATION    = "duration"
-	RETRY_COUNT = "retry_count"
-	MASKED      = "masked"
+	DURATION       = "duration"
+	RETRY_COUNT    = "retry_count"
+	MASKED         = "masked"
+	DEGRADED       = "degraded"
+	ORIGINAL_ERROR = "original_error"
 )
 
 type RepoOp[In any, Out any] func(ctx context.Context, input In) (OutputWithMeta[Out], error)
@@ -45,31 +48,94 @@ func GetMeta(ctx context.Context, key string) (interface{}, bool) {
 	return val, exists
 }
 
-// Per-request overrides
+// Overrides holds the per-request settings consulted by the built-in middlewares.
+// A disabled built-in passes the call straight to the next middleware.
+type Overrides struct {
+	DisableLogging      bool
+	DisableTiming       bool
+	DisableOutputResult bool
+	DisableMasking      bool
+	DisableTracing      bool
+	DisableRetry        bool
+	DisableFallback     bool
+	DisableFaults       bool
+	DisableShadow       bool
+	DisableDualWrite    bool
+
+	// Retry replaces the retry count and delay configured on Retry when set.
+	Retry *RetryOverride
+}
+
+type RetryOverride struct {
+	MaxRetries int
+	Delay      time.Duration
+}
+
 type ctxKey int
 
 const (
-	ckDisableLogging ctxKey = iota
-	ckDisableTiming
-	ckDisableOutputResult
-	ckDisableMasking
-	ckDisableTracing
+	ckOverrides ctxKey = iota
 )
 
+// WithOverrides replaces the overrides carried by ctx.
+func WithOverrides(ctx context.Context, o Overrides) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, ckOverrides, o)
+}
+
+// OverridesFrom returns the overrides carried by ctx, or the zero Overrides.
+func OverridesFrom(ctx context.Context) Overrides {
+	if ctx == nil {
+		return Overrides{}
+	}
+	o, _ := ctx.Value(ckOverrides).(Overrides)
+	return o
+}
+
+func updateOverrides(ctx context.Context, fn func(o *Overrides)) context.Context {
+	o := OverridesFrom(ctx)
+	fn(&o)
+	return WithOverrides(ctx, o)
+}
+
+// WithRetryOverride replaces the retry count and delay for this request.
+func WithRetryOverride(ctx context.Context, maxRetries int, delay time.Duration) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) {
+		o.Retry = &RetryOverride{MaxRetries: maxRetries, Delay: delay}
+	})
+}
+
 func DisableLogging(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableLogging, true)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableLogging = true })
 }
 func DisableTiming(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableTiming, true)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableTiming = true })
 }
 func DisableOutputResult(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableOutputResult, true)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableOutputResult = true })
 }
 func DisableTracing(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableTracing, true)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableTracing = true })
 }
 func DisableMasking(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableMasking, true)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableMasking = true })
+}
+func DisableRetry(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableRetry = true })
+}
+func DisableFallback(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableFallback = true })
+}
+func DisableFaults(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableFaults = true })
+}
+func DisableShadow(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableShadow = true })
+}
+func DisableDualWrite(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableDualWrite = true })
 }
 func DisableAll(ctx context.Context) context.Context {
 	ctx = DisableLogging(ctx)
@@ -77,22 +143,42 @@ func DisableAll(ctx context.Context) context.Context {
 	ctx = DisableOutputResult(ctx)
 	ctx = DisableMasking(ctx)
 	ctx = DisableTracing(ctx)
+	ctx = DisableRetry(ctx)
+	ctx = DisableFallback(ctx)
+	ctx = DisableFaults(ctx)
+	ctx = DisableShadow(ctx)
+	ctx = DisableDualWrite(ctx)
 	return ctx
 }
 func EnableLogging(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableLogging, false)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableLogging = false })
 }
 func EnableTiming(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableTiming, false)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableTiming = false })
 }
 func EnableOutputResult(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableOutputResult, false)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableOutputResult = false })
 }
 func EnableMasking(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableMasking, false)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableMasking = false })
 }
 func EnableTracing(ctx context.Context) context.Context {
-	return context.WithValue(ctx, ckDisableTracing, false)
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableTracing = false })
+}
+func EnableRetry(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableRetry = false })
+}
+func EnableFallback(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableFallback = false })
+}
+func EnableFaults(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableFaults = false })
+}
+func EnableShadow(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableShadow = false })
+}
+func EnableDualWrite(ctx context.Context) context.Context {
+	return updateOverrides(ctx, func(o *Overrides) { o.DisableDualWrite = false })
 }
 func EnableAll(ctx context.Context) context.Context {
 	ctx = EnableLogging(ctx)
@@ -100,32 +186,43 @@ func EnableAll(ctx context.Context) context.Context {
 	ctx = EnableOutputResult(ctx)
 	ctx = EnableMasking(ctx)
 	ctx = EnableTracing(ctx)
+	ctx = EnableRetry(ctx)
+	ctx = EnableFallback(ctx)
+	ctx = EnableFaults(ctx)
+	ctx = EnableShadow(ctx)
+	ctx = EnableDualWrite(ctx)
 	return ctx
 }
 
 func IsLoggingDisabled(ctx context.Context) bool {
-	disabled, ok := ctx.Value(ckDisableLogging).(bool)
-	return ok && disabled
+	return OverridesFrom(ctx).DisableLogging
 }
 func IsTimingDisabled(ctx context.Context) bool {
-	disabled, ok := ctx.Value(ckDisableTiming).(bool)
-	return ok && disabled
+	return OverridesFrom(ctx).DisableTiming
 }
 func IsOutputResultDisabled(ctx context.Context) bool {
-	disabled, ok := ctx.Value(ckDisableOutputResult).(bool)
-	return ok && disabled
+	return OverridesFrom(ctx).DisableOutputResult
 }
 func IsMaskingDisabled(ctx context.Context) bool {
-	disabled, ok := ctx.Value(ckDisableMasking).(bool)
-	return ok && disabled
+	return OverridesFrom(ctx).DisableMasking
 }
 func IsTracingDisabled(ctx context.Context) bool {
-	disabled, ok := ctx.Value(ckDisableTracing).(bool)
-	return ok && disabled
+	return OverridesFrom(ctx).DisableTracing
 }
 func IsRetryDisabled(ctx context.Context) bool {
-	disabled, ok := ctx.Value(ckDisableTracing).(bool)
-	return ok && disabled
+	return OverridesFrom(ctx).DisableRetry
+}
+func IsFallbackDisabled(ctx context.Context) bool {
+	return OverridesFrom(ctx).DisableFallback
+}
+func IsFaultInjectionDisabled(ctx context.Context) bool {
+	return OverridesFrom(ctx).DisableFaults
+}
+func IsShadowDisabled(ctx context.Context) bool {
+	return OverridesFrom(ctx).DisableShadow
+}
+func IsDualWriteDisabled(ctx context.Context) bool {
+	return OverridesFrom(ctx).DisableDualWrite
 }
 
 // Gate composes a middleware but short-circuits to `next` when disabledFn(ctx) == true.
@@ -156,6 +253,9 @@ func Gate[In any, Out any](mw Middleware[In, Out], disabledFn func(ctx context.C
 func Timer[In any, Out any]() Middleware[In, Out] {
 	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
 		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsTimingDisabled(ctx) {
+				return next(ctx, input)
+			}
 			start := time.Now()
 			out, err := next(ctx, input)
 			if out.Meta == nil {
@@ -170,6 +270,9 @@ func Timer[In any, Out any]() Middleware[In, Out] {
 func Logging[In any, Out any](logger func(ctx context.Context, msg string)) Middleware[In, Out] {
 	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
 		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsLoggingDisabled(ctx) {
+				return next(ctx, input)
+			}
 			logger(ctx, fmt.Sprintf("🟢 [START] Operation\n  ↳ Input: %+v", input))
 
 			out, err := next(ctx, input)
@@ -197,6 +300,9 @@ func Logging[In any, Out any](logger func(ctx context.Context, msg string)) Midd
 func Tracing[In any, Out any]() Middleware[In, Out] {
 	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
 		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsTracingDisabled(ctx) {
+				return next(ctx, input)
+			}
 			// inject tracing logic here
 			return next(ctx, input)
 		}
@@ -204,15 +310,30 @@ func Tracing[In any, Out any]() Middleware[In, Out] {
 }
 
 func Retry[In any, Out any](maxRetries int, retryDelay time.Duration) Middleware[In, Out] {
+	return RetryIf[In, Out](maxRetries, retryDelay, nil)
+}
+
+// RetryIf is Retry for the errors accepted by retryable (a nil retryable accepts every
+// error). Other errors are returned at once, so permanent failures such as invalid
+// input are not repeated.
+func RetryIf[In any, Out any](maxRetries int, retryDelay time.Duration, retryable func(err error) bool) Middleware[In, Out] {
 	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
 		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsRetryDisabled(ctx) {
+				return next(ctx, input)
+			}
+			maxRetries, retryDelay := maxRetries, retryDelay
+			if o := OverridesFrom(ctx).Retry; o != nil {
+				maxRetries, retryDelay = o.MaxRetries, o.Delay
+			}
+
 			var out OutputWithMeta[Out]
 			var err error
 			retries := 0
 
 			for {
 				out, err = next(ctx, input)
-				if err == nil || retries >= maxRetries {
+				if err == nil || retries >= maxRetries || (retryable != nil && !retryable(err)) {
 					break
 				}
 				retries++
@@ -229,10 +350,43 @@ func Retry[In any, Out any](maxRetries int, retryDelay time.Duration) Middleware
 	}
 }
 
+// Fallback calls an alternative RepoOp when the downstream chain fails with an error
+// accepted by classify (a nil classify accepts every error). A successful fallback is
+// returned with DEGRADED set and the primary error kept under ORIGINAL_ERROR in Meta.
+// If the fallback also fails, both errors are returned joined.
+func Fallback[In any, Out any](classify func(err error) bool, fallback RepoOp[In, Out]) Middleware[In, Out] {
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsFallbackDisabled(ctx) {
+				return next(ctx, input)
+			}
+			out, err := next(ctx, input)
+			if err == nil || fallback == nil || (classify != nil && !classify(err)) {
+				return out, err
+			}
+
+			alt, altErr := fallback(ctx, input)
+			if altErr != nil {
+				return out, errors.Join(err, altErr)
+			}
+
+			if alt.Meta == nil {
+				alt.Meta = make(map[string]interface{})
+			}
+			alt.Meta[DEGRADED] = true
+			alt.Meta[ORIGINAL_ERROR] = err
+			return alt, nil
+		}
+	}
+}
+
 // OutputResult processes the output of the RepoOp and logs or modifies it as needed.
 func OutputResult[In any, Out any](callback func(output Out, meta map[string]interface{}, err error)) Middleware[In, Out] {
 	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
 		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsOutputResultDisabled(ctx) {
+				return next(ctx, input)
+			}
 			out, err := next(ctx, input)
 			if callback != nil {
 				callback(out.Data, out.Meta, err)
@@ -246,6 +400,9 @@ func OutputResult[In any, Out any](callback func(output Out, meta map[string]int
 func MaskOutput[In any, Out any](maskFunc func(output Out) Out) Middleware[In, Out] {
 	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
 		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsMaskingDisabled(ctx) {
+				return next(ctx, input)
+			}
 			// Call the next middleware or base operation
 			out, err := next(ctx, input)
 			if err == nil && maskFunc != nil {
@@ -1,7 +1,59 @@
 package infra
 
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Names of the built-in middlewares as recorded by MiddlewareBuilder.
+const (
+	MW_LOGGING       = "Logging"
+	MW_TIMER         = "Timer"
+	MW_OUTPUT_RESULT = "OutputResult"
+	MW_MASK_OUTPUT   = "MaskOutput"
+	MW_RETRY         = "Retry"
+	MW_TRACING       = "Tracing"
+	MW_FALLBACK      = "Fallback"
+	MW_FAULTS        = "FaultInjection"
+	MW_SHADOW        = "Shadow"
+	MW_DUAL_WRITE    = "DualWrite"
+)
+
+var (
+	ErrMiddlewareNotFound  = errors.New("middleware not found")
+	ErrDuplicateMiddleware = errors.New("middleware already registered")
+	ErrInvalidPosition     = errors.New("invalid middleware position")
+)
+
+// Kinds of gate recorded on a MiddlewareEntry.
+const (
+	GATE_UNLESS = "unless" // Gate: skipped when the context predicate holds
+	GATE_WHEN   = "when"   // GateOn: applied when the Condition holds
+	GATE_AFTER  = "after"  // GateAfter: applied when the PostCondition holds for the outcome
+)
+
+// MiddlewareEntry is a single named middleware in a builder. Condition holds the
+// name of the gate predicate and GateKind how it applies when the entry was added
+// through AddGate, AddWhen or AddAfter.
+type MiddlewareEntry[In any, Out any] struct {
+	Name       string
+	Condition  string
+	GateKind   string
+	Middleware Middleware[In, Out]
+	// unnamed marks entries added through Add, which Merge may rename.
+	unnamed bool
+}
+
 type MiddlewareBuilder[In any, Out any] struct {
-	middlewares []Middleware[In, Out]
+	middlewares []MiddlewareEntry[In, Out]
+	// unnamed numbers the entries added through Add. It only grows, so a name is not
+	// reused after an entry is removed.
+	unnamed int
 }
 
 // Chain composes middlewares around a base operation.
@@ -12,10 +64,318 @@ func Chain[In any, Out any](base RepoOp[In, Out], mws ...Middleware[In, Out]) Re
 	return base
 }
 
+// Named returns an entry for mw under the given name.
+func Named[In any, Out any](name string, mw Middleware[In, Out]) MiddlewareEntry[In, Out] {
+	return MiddlewareEntry[In, Out]{Name: name, Middleware: mw}
+}
+
+// Gated returns an entry for mw wrapped in Gate, recording the name of disabledFn as its condition.
+func Gated[In any, Out any](name string, mw Middleware[In, Out], disabledFn func(ctx context.Context) bool) MiddlewareEntry[In, Out] {
+	return MiddlewareEntry[In, Out]{
+		Name:       name,
+		Condition:  funcName(disabledFn),
+		GateKind:   GATE_UNLESS,
+		Middleware: Gate(mw, disabledFn),
+	}
+}
+
+// GatedOn returns an entry for mw wrapped in GateOn, recording the name of cond as its condition.
+func GatedOn[In any, Out any](name string, mw Middleware[In, Out], cond Condition[In]) MiddlewareEntry[In, Out] {
+	return MiddlewareEntry[In, Out]{
+		Name:       name,
+		Condition:  funcName(cond),
+		GateKind:   GATE_WHEN,
+		Middleware: GateOn(mw, cond),
+	}
+}
+
+// GatedAfter returns an entry for mw wrapped in GateAfter, recording the name of cond as
+// its condition.
+func GatedAfter[In any, Out any](name string, mw Middleware[In, Out], cond PostCondition[In, Out]) MiddlewareEntry[In, Out] {
+	return MiddlewareEntry[In, Out]{
+		Name:       name,
+		Condition:  funcName(cond),
+		GateKind:   GATE_AFTER,
+		Middleware: GateAfter(mw, cond),
+	}
+}
+
+// Add appends an unnamed middleware. It is recorded as "middleware#<n>", where n
+// counts the unnamed middlewares ever added to b.
 func (b *MiddlewareBuilder[In, Out]) Add(mw Middleware[In, Out]) {
-	b.middlewares = append(b.middlewares, mw)
+	e := Named(b.nextUnnamed(nil), mw)
+	e.unnamed = true
+	b.middlewares = append(b.middlewares, e)
+}
+
+// nextUnnamed returns the next "middleware#<n>" name not registered in b nor in taken.
+func (b *MiddlewareBuilder[In, Out]) nextUnnamed(taken map[string]bool) string {
+	for {
+		name := fmt.Sprintf("middleware#%d", b.unnamed)
+		b.unnamed++
+		if !b.Has(name) && !taken[name] {
+			return name
+		}
+	}
+}
+
+// AddNamed appends a middleware under the given name. The Add* methods fail with
+// ErrDuplicateMiddleware when the name is already registered, leaving b unchanged.
+func (b *MiddlewareBuilder[In, Out]) AddNamed(name string, mw Middleware[In, Out]) error {
+	return b.append(Named(name, mw))
+}
+
+// AddGate appends mw wrapped in Gate and records the name of disabledFn as its condition.
+func (b *MiddlewareBuilder[In, Out]) AddGate(name string, mw Middleware[In, Out], disabledFn func(ctx context.Context) bool) error {
+	return b.append(Gated(name, mw, disabledFn))
+}
+
+// AddWhen appends mw wrapped in GateOn and records the name of cond as its condition.
+func (b *MiddlewareBuilder[In, Out]) AddWhen(name string, mw Middleware[In, Out], cond Condition[In]) error {
+	return b.append(GatedOn(name, mw, cond))
+}
+
+// AddAfter appends mw wrapped in GateAfter and records the name of cond as its condition.
+func (b *MiddlewareBuilder[In, Out]) AddAfter(name string, mw Middleware[In, Out], cond PostCondition[In, Out]) error {
+	return b.append(GatedAfter(name, mw, cond))
+}
+
+func (b *MiddlewareBuilder[In, Out]) append(e MiddlewareEntry[In, Out]) error {
+	return b.Insert(len(b.middlewares), e)
+}
+
+// Has reports whether an entry with the given name is registered.
+func (b *MiddlewareBuilder[In, Out]) Has(name string) bool {
+	return b.indexOf(name) >= 0
+}
+
+// Insert places e at position index (0 is outermost).
+func (b *MiddlewareBuilder[In, Out]) Insert(index int, e MiddlewareEntry[In, Out]) error {
+	if index < 0 || index > len(b.middlewares) {
+		return fmt.Errorf("%w: %d", ErrInvalidPosition, index)
+	}
+	if b.Has(e.Name) {
+		return fmt.Errorf("%w: %s", ErrDuplicateMiddleware, e.Name)
+	}
+	b.middlewares = append(b.middlewares, MiddlewareEntry[In, Out]{})
+	copy(b.middlewares[index+1:], b.middlewares[index:])
+	b.middlewares[index] = e
+	return nil
+}
+
+// InsertBefore places e directly outside the entry named target.
+func (b *MiddlewareBuilder[In, Out]) InsertBefore(target string, e MiddlewareEntry[In, Out]) error {
+	i := b.indexOf(target)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrMiddlewareNotFound, target)
+	}
+	return b.Insert(i, e)
+}
+
+// InsertAfter places e directly inside the entry named target.
+func (b *MiddlewareBuilder[In, Out]) InsertAfter(target string, e MiddlewareEntry[In, Out]) error {
+	i := b.indexOf(target)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrMiddlewareNotFound, target)
+	}
+	return b.Insert(i+1, e)
+}
+
+// Remove drops the entry with the given name.
+func (b *MiddlewareBuilder[In, Out]) Remove(name string) error {
+	i := b.indexOf(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrMiddlewareNotFound, name)
+	}
+	b.middlewares = append(b.middlewares[:i], b.middlewares[i+1:]...)
+	return nil
+}
+
+// Replace swaps the entry with the given name for e, keeping its position.
+func (b *MiddlewareBuilder[In, Out]) Replace(name string, e MiddlewareEntry[In, Out]) error {
+	i := b.indexOf(name)
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrMiddlewareNotFound, name)
+	}
+	if e.Name != name && b.Has(e.Name) {
+		return fmt.Errorf("%w: %s", ErrDuplicateMiddleware, e.Name)
+	}
+	b.middlewares[i] = e
+	return nil
+}
+
+// Clone returns an independent copy of the builder.
+func (b *MiddlewareBuilder[In, Out]) Clone() *MiddlewareBuilder[In, Out] {
+	return &MiddlewareBuilder[In, Out]{middlewares: b.Entries(), unnamed: b.unnamed}
+}
+
+// Merge appends the entries of other inside the entries of b. Entries other added
+// through Add are renumbered with b's counter, so both builders may use Add. Other names
+// already present in b are rejected and b is left unchanged.
+func (b *MiddlewareBuilder[In, Out]) Merge(other *MiddlewareBuilder[In, Out]) error {
+	taken := make(map[string]bool)
+	for _, e := range other.middlewares {
+		if e.unnamed {
+			continue
+		}
+		if b.Has(e.Name) {
+			return fmt.Errorf("%w: %s", ErrDuplicateMiddleware, e.Name)
+		}
+		taken[e.Name] = true
+	}
+	for _, e := range other.middlewares {
+		if e.unnamed {
+			e.Name = b.nextUnnamed(taken)
+			taken[e.Name] = true
+		}
+		b.middlewares = append(b.middlewares, e)
+	}
+	return nil
+}
+
+func (b *MiddlewareBuilder[In, Out]) indexOf(name string) int {
+	for i, e := range b.middlewares {
+		if e.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Entries returns a copy of the recorded entries, outermost first.
+func (b *MiddlewareBuilder[In, Out]) Entries() []MiddlewareEntry[In, Out] {
+	return append([]MiddlewareEntry[In, Out](nil), b.middlewares...)
 }
 
 func (b *MiddlewareBuilder[In, Out]) Build(base RepoOp[In, Out]) RepoOp[In, Out] {
-	return Chain(base, b.middlewares...)
+	mws := make([]Middleware[In, Out], len(b.middlewares))
+	for i, e := range b.middlewares {
+		mws[i] = e.Middleware
+	}
+	return Chain(base, mws...)
+}
+
+// Describe returns the effective chain, outermost first, along with any ordering
+// issues reported by Validate.
+func (b *MiddlewareBuilder[In, Out]) Describe() ChainDescription {
+	d := ChainDescription{Issues: b.Validate()}
+	for _, e := range b.middlewares {
+		d.Entries = append(d.Entries, EntryDescription{Name: e.Name, Condition: e.Condition, GateKind: e.GateKind})
+	}
+	return d
+}
+
+// Validate checks the chain against DefaultOrderingRules.
+func (b *MiddlewareBuilder[In, Out]) Validate() []OrderingIssue {
+	return b.ValidateWith(DefaultOrderingRules)
+}
+
+// ValidateWith checks the chain against the given rules.
+func (b *MiddlewareBuilder[In, Out]) ValidateWith(rules []OrderingRule) []OrderingIssue {
+	names := make([]string, len(b.middlewares))
+	for i, e := range b.middlewares {
+		names[i] = e.Name
+	}
+	return orderingIssues(names, rules)
+}
+
+// orderingIssues checks a chain, given by its entry names from outermost, against rules.
+func orderingIssues(names []string, rules []OrderingRule) []OrderingIssue {
+	var issues []OrderingIssue
+	for _, rule := range rules {
+		for i, outer := range names {
+			if outer != rule.Outer {
+				continue
+			}
+			for _, inner := range names[i+1:] {
+				if inner == rule.Inner {
+					issues = append(issues, OrderingIssue{Rule: rule})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// EntryDescription is the non-generic view of a MiddlewareEntry.
+type EntryDescription struct {
+	Name      string
+	Condition string
+	GateKind  string
+}
+
+// ChainDescription describes a built chain so it can be inspected or logged at runtime.
+type ChainDescription struct {
+	Entries []EntryDescription
+	Issues  []OrderingIssue
+}
+
+func (d ChainDescription) String() string {
+	parts := make([]string, 0, len(d.Entries)+1)
+	for _, e := range d.Entries {
+		if e.Condition != "" {
+			kind := cmp.Or(e.GateKind, GATE_UNLESS)
+			parts = append(parts, fmt.Sprintf("%s [%s %s]", e.Name, kind, e.Condition))
+		} else {
+			parts = append(parts, e.Name)
+		}
+	}
+	parts = append(parts, "base")
+	return strings.Join(parts, " -> ")
+}
+
+// OrderingRule flags a chain where Inner is wrapped by Outer, i.e. Inner was added after Outer.
+type OrderingRule struct {
+	Outer  string
+	Inner  string
+	Reason string
+}
+
+type OrderingIssue struct {
+	Rule OrderingRule
+}
+
+func (i OrderingIssue) String() string {
+	return fmt.Sprintf("%s inside %s: %s", i.Rule.Inner, i.Rule.Outer, i.Rule.Reason)
+}
+
+// DefaultOrderingRules lists the orderings of built-in middlewares known to be hazardous.
+// Middlewares see the output after every middleware inside them has run, so anything that
+// observes output must sit outside MaskOutput to only ever see masked data.
+var DefaultOrderingRules = []OrderingRule{
+	{Outer: MW_MASK_OUTPUT, Inner: MW_OUTPUT_RESULT, Reason: "output callback receives unmasked data"},
+	{Outer: MW_MASK_OUTPUT, Inner: MW_LOGGING, Reason: "logging writes unmasked output"},
+	{Outer: MW_RETRY, Inner: MW_TIMER, Reason: "duration only covers the last attempt"},
+	{Outer: MW_RETRY, Inner: MW_FALLBACK, Reason: "fallback answers before retries are attempted"},
+	{Outer: MW_SHADOW, Inner: MW_MASK_OUTPUT, Reason: "shadow compares masked primary output with unmasked secondary output"},
+}
+
+// funcName returns the short name of fn, e.g. "IsTimingDisabled". Closures are named
+// after the function that returned them, so CallerIn[...].func1 becomes "CallerIn".
+func funcName(fn any) string {
+	if fn == nil {
+		return ""
+	}
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return ""
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "[...]"); i >= 0 {
+		name = name[:i] + name[i+len("[...]"):]
+	}
+	if i := strings.Index(name, ".func"); i >= 0 {
+		name = name[:i]
+	}
+	return name
 }
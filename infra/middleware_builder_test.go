@@ -0,0 +1,172 @@
+package infra
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tag returns a middleware appending name to the output, so a built chain shows the
+// order its middlewares ran in, innermost first.
+func tag(name string) Middleware[int, []string] {
+	return func(next RepoOp[int, []string]) RepoOp[int, []string] {
+		return func(ctx context.Context, in int) (OutputWithMeta[[]string], error) {
+			out, err := next(ctx, in)
+			out.Data = append(out.Data, name)
+			return out, err
+		}
+	}
+}
+
+func names(b *MiddlewareBuilder[int, []string]) []string {
+	var out []string
+	for _, e := range b.Entries() {
+		out = append(out, e.Name)
+	}
+	return out
+}
+
+func newTagBuilder(tags ...string) *MiddlewareBuilder[int, []string] {
+	b := &MiddlewareBuilder[int, []string]{}
+	for _, name := range tags {
+		b.AddNamed(name, tag(name))
+	}
+	return b
+}
+
+func TestMiddlewareBuilderAddNamesAreUnique(t *testing.T) {
+	b := &MiddlewareBuilder[int, []string]{}
+	b.Add(tag("a"))
+	b.Add(tag("b"))
+	require.NoError(t, b.Remove("middleware#0"))
+	b.Add(tag("c"))
+	assert.Equal(t, []string{"middleware#1", "middleware#2"}, names(b))
+
+	b.AddNamed("middleware#3", tag("d"))
+	b.Add(tag("e"))
+	assert.Equal(t, []string{"middleware#1", "middleware#2", "middleware#3", "middleware#4"}, names(b))
+
+	clone := b.Clone()
+	clone.Add(tag("f"))
+	assert.Equal(t, "middleware#5", names(clone)[4])
+}
+
+func TestMiddlewareBuilderMergeRenamesUnnamed(t *testing.T) {
+	b := &MiddlewareBuilder[int, []string]{}
+	b.Add(tag("a"))
+	b.Add(tag("b"))
+	other := &MiddlewareBuilder[int, []string]{}
+	other.Add(tag("x"))
+	require.NoError(t, other.AddNamed("middleware#2", tag("y")))
+
+	require.NoError(t, b.Merge(other))
+	assert.Equal(t, []string{"middleware#0", "middleware#1", "middleware#3", "middleware#2"}, names(b))
+	assert.Equal(t, []string{"middleware#0", "middleware#2"}, names(other), "merge leaves the other builder alone")
+
+	b.Add(tag("z"))
+	assert.Equal(t, "middleware#4", names(b)[4])
+}
+
+func TestMiddlewareBuilderEditing(t *testing.T) {
+	cases := []struct {
+		name string
+		edit func(b *MiddlewareBuilder[int, []string]) error
+		want []string
+		err  error
+	}{
+		{"insert first", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Insert(0, Named("x", tag("x")))
+		}, []string{"x", "a", "b", "c"}, nil},
+		{"insert last", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Insert(3, Named("x", tag("x")))
+		}, []string{"a", "b", "c", "x"}, nil},
+		{"insert out of range", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Insert(4, Named("x", tag("x")))
+		}, []string{"a", "b", "c"}, ErrInvalidPosition},
+		{"insert duplicate", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Insert(0, Named("b", tag("b")))
+		}, []string{"a", "b", "c"}, ErrDuplicateMiddleware},
+		{"insert before", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.InsertBefore("b", Named("x", tag("x")))
+		}, []string{"a", "x", "b", "c"}, nil},
+		{"insert after", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.InsertAfter("c", Named("x", tag("x")))
+		}, []string{"a", "b", "c", "x"}, nil},
+		{"insert before missing", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.InsertBefore("z", Named("x", tag("x")))
+		}, []string{"a", "b", "c"}, ErrMiddlewareNotFound},
+		{"insert after missing", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.InsertAfter("z", Named("x", tag("x")))
+		}, []string{"a", "b", "c"}, ErrMiddlewareNotFound},
+		{"remove", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Remove("b")
+		}, []string{"a", "c"}, nil},
+		{"replace", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Replace("b", Named("x", tag("x")))
+		}, []string{"a", "x", "c"}, nil},
+		{"replace keeping name", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Replace("b", Named("b", tag("b2")))
+		}, []string{"a", "b", "c"}, nil},
+		{"replace with existing name", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Replace("b", Named("c", tag("c")))
+		}, []string{"a", "b", "c"}, ErrDuplicateMiddleware},
+		{"replace missing", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Replace("z", Named("x", tag("x")))
+		}, []string{"a", "b", "c"}, ErrMiddlewareNotFound},
+		{"merge", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Merge(newTagBuilder("x", "y"))
+		}, []string{"a", "b", "c", "x", "y"}, nil},
+		{"merge duplicate", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.Merge(newTagBuilder("x", "a"))
+		}, []string{"a", "b", "c"}, ErrDuplicateMiddleware},
+		{"add named duplicate", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.AddNamed("b", tag("b"))
+		}, []string{"a", "b", "c"}, ErrDuplicateMiddleware},
+		{"add gate duplicate", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.AddGate("c", tag("c"), func(context.Context) bool { return false })
+		}, []string{"a", "b", "c"}, ErrDuplicateMiddleware},
+		{"add when duplicate", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.AddWhen("a", tag("a"), func(context.Context, int) bool { return true })
+		}, []string{"a", "b", "c"}, ErrDuplicateMiddleware},
+		{"add after duplicate", func(b *MiddlewareBuilder[int, []string]) error {
+			return b.AddAfter("a", tag("a"), func(context.Context, int, Outcome[[]string]) bool { return true })
+		}, []string{"a", "b", "c"}, ErrDuplicateMiddleware},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := newTagBuilder("a", "b", "c")
+			err := tc.edit(b)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.want, names(b))
+		})
+	}
+}
+
+func TestMiddlewareBuilderBuildOrder(t *testing.T) {
+	b := newTagBuilder("a", "b", "c")
+	require.NoError(t, b.Replace("b", Named("b", tag("b2"))))
+	base := func(ctx context.Context, in int) (OutputWithMeta[[]string], error) {
+		return OutputWithMeta[[]string]{Data: []string{"base"}}, nil
+	}
+
+	out, err := b.Build(base)(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"base", "c", "b2", "a"}, out.Data, "the first entry is outermost")
+}
+
+func TestMiddlewareBuilderCloneIsIndependent(t *testing.T) {
+	b := newTagBuilder("a", "b")
+	clone := b.Clone()
+	require.NoError(t, clone.Remove("a"))
+	require.NoError(t, clone.Replace("b", Named("x", tag("x"))))
+	clone.AddNamed("y", tag("y"))
+
+	assert.Equal(t, []string{"a", "b"}, names(b))
+	assert.Equal(t, []string{"x", "y"}, names(clone))
+}
@@ -0,0 +1,183 @@
+package infra
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// Condition decides, before the call, whether a middleware should run for this request.
+// Unlike the predicate given to Gate, true means "apply the middleware".
+type Condition[In any] func(ctx context.Context, input In) bool
+
+// PostCondition decides, after the call, whether a middleware should run for the outcome.
+type PostCondition[In any, Out any] func(ctx context.Context, input In, outcome Outcome[Out]) bool
+
+// Outcome is the result of the downstream chain as seen by a PostCondition.
+type Outcome[Out any] struct {
+	Output  OutputWithMeta[Out]
+	Err     error
+	Elapsed time.Duration
+}
+
+// now is the clock used by time based conditions.
+var now = time.Now
+
+// GateOn runs mw only when cond holds, otherwise calls next directly.
+func GateOn[In any, Out any](mw Middleware[In, Out], cond Condition[In]) Middleware[In, Out] {
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		wrapped := mw(next)
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if cond != nil && !cond(ctx, input) {
+				return next(ctx, input)
+			}
+			return wrapped(ctx, input)
+		}
+	}
+}
+
+// GateAfter calls next first and only runs mw when cond holds for the outcome, e.g.
+// to log slow calls only. mw is run around a replay of the recorded outcome, so
+// middlewares that re-invoke next (Retry) or measure time (Timer) see the replay,
+// not a second call to the repository.
+func GateAfter[In any, Out any](mw Middleware[In, Out], cond PostCondition[In, Out]) Middleware[In, Out] {
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			start := now()
+			out, err := next(ctx, input)
+			outcome := Outcome[Out]{Output: out, Err: err, Elapsed: now().Sub(start)}
+			if cond == nil || !cond(ctx, input, outcome) {
+				return out, err
+			}
+			return mw(replay[In](out, err))(ctx, input)
+		}
+	}
+}
+
+// replay returns a RepoOp that always answers with the given result.
+func replay[In any, Out any](out OutputWithMeta[Out], err error) RepoOp[In, Out] {
+	return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+		return out, err
+	}
+}
+
+// FromContext adapts a context predicate such as IsLoggingDisabled into a Condition.
+func FromContext[In any](fn func(ctx context.Context) bool) Condition[In] {
+	return func(ctx context.Context, input In) bool {
+		return fn(ctx)
+	}
+}
+
+// InputMatches holds when fn accepts the input, e.g. only names longer than N.
+func InputMatches[In any](fn func(input In) bool) Condition[In] {
+	return func(ctx context.Context, input In) bool {
+		return fn(input)
+	}
+}
+
+// Percentage holds for roughly percent (0-100) of calls.
+func Percentage[In any](percent float64) Condition[In] {
+	return func(ctx context.Context, input In) bool {
+		return rand.Float64()*100 < percent
+	}
+}
+
+// DailyWindow holds between from and to, given as offsets from local midnight.
+// A window where to is before from wraps around midnight.
+func DailyWindow[In any](from, to time.Duration) Condition[In] {
+	return func(ctx context.Context, input In) bool {
+		t := now()
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		offset := t.Sub(midnight)
+		if from <= to {
+			return offset >= from && offset < to
+		}
+		return offset >= from || offset < to
+	}
+}
+
+type callerKey struct{}
+
+// WithCaller records the identity of the caller on the context.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the caller identity recorded by WithCaller.
+func CallerFrom(ctx context.Context) (string, bool) {
+	caller, ok := ctx.Value(callerKey{}).(string)
+	return caller, ok
+}
+
+// CallerIn holds when the caller recorded on the context is one of callers.
+func CallerIn[In any](callers ...string) Condition[In] {
+	allowed := make(map[string]struct{}, len(callers))
+	for _, c := range callers {
+		allowed[c] = struct{}{}
+	}
+	return func(ctx context.Context, input In) bool {
+		caller, ok := CallerFrom(ctx)
+		if !ok {
+			return false
+		}
+		_, ok = allowed[caller]
+		return ok
+	}
+}
+
+// And holds when every condition holds.
+func And[In any](conds ...Condition[In]) Condition[In] {
+	return func(ctx context.Context, input In) bool {
+		for _, c := range conds {
+			if !c(ctx, input) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or holds when any condition holds.
+func Or[In any](conds ...Condition[In]) Condition[In] {
+	return func(ctx context.Context, input In) bool {
+		for _, c := range conds {
+			if c(ctx, input) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not inverts a condition.
+func Not[In any](cond Condition[In]) Condition[In] {
+	return func(ctx context.Context, input In) bool {
+		return !cond(ctx, input)
+	}
+}
+
+// OnError holds when the call failed.
+func OnError[In any, Out any]() PostCondition[In, Out] {
+	return func(ctx context.Context, input In, outcome Outcome[Out]) bool {
+		return outcome.Err != nil
+	}
+}
+
+// SlowerThan holds when the call took longer than threshold.
+func SlowerThan[In any, Out any](threshold time.Duration) PostCondition[In, Out] {
+	return func(ctx context.Context, input In, outcome Outcome[Out]) bool {
+		return outcome.Elapsed > threshold
+	}
+}
+
+// AnyOutcome holds when any of the post-conditions holds.
+func AnyOutcome[In any, Out any](conds ...PostCondition[In, Out]) PostCondition[In, Out] {
+	return func(ctx context.Context, input In, outcome Outcome[Out]) bool {
+		for _, c := range conds {
+			if c(ctx, input, outcome) {
+				return true
+			}
+		}
+		return false
+	}
+}
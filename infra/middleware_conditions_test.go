@@ -0,0 +1,168 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock replaces now for the test. Every call returns the current time and then
+// advances it by step.
+func fakeClock(t *testing.T, start time.Time, step time.Duration) {
+	t.Helper()
+	orig := now
+	t.Cleanup(func() { now = orig })
+	current := start
+	now = func() time.Time {
+		t := current
+		current = current.Add(step)
+		return t
+	}
+}
+
+func at(hour, minute int) time.Time {
+	return time.Date(2024, 3, 9, hour, minute, 0, 0, time.UTC)
+}
+
+func TestDailyWindow(t *testing.T) {
+	cases := []struct {
+		name     string
+		from, to time.Duration
+		now      time.Time
+		want     bool
+	}{
+		{"inside", 9 * time.Hour, 17 * time.Hour, at(12, 0), true},
+		{"start is inclusive", 9 * time.Hour, 17 * time.Hour, at(9, 0), true},
+		{"end is exclusive", 9 * time.Hour, 17 * time.Hour, at(17, 0), false},
+		{"before", 9 * time.Hour, 17 * time.Hour, at(8, 59), false},
+		{"wrap before midnight", 22 * time.Hour, 2 * time.Hour, at(23, 30), true},
+		{"wrap after midnight", 22 * time.Hour, 2 * time.Hour, at(1, 59), true},
+		{"wrap at midnight", 22 * time.Hour, 2 * time.Hour, at(0, 0), true},
+		{"wrap end is exclusive", 22 * time.Hour, 2 * time.Hour, at(2, 0), false},
+		{"outside wrap", 22 * time.Hour, 2 * time.Hour, at(12, 0), false},
+		{"empty window", 9 * time.Hour, 9 * time.Hour, at(9, 0), false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClock(t, tc.now, 0)
+			assert.Equal(t, tc.want, DailyWindow[int](tc.from, tc.to)(context.Background(), 0))
+		})
+	}
+}
+
+func TestConditions(t *testing.T) {
+	yes := func(ctx context.Context, in int) bool { return true }
+	no := func(ctx context.Context, in int) bool { return false }
+	ctx := context.Background()
+
+	cases := []struct {
+		name string
+		cond Condition[int]
+		ctx  context.Context
+		in   int
+		want bool
+	}{
+		{"and all", And[int](yes, yes), ctx, 0, true},
+		{"and one fails", And[int](yes, no), ctx, 0, false},
+		{"and empty", And[int](), ctx, 0, true},
+		{"or one holds", Or[int](no, yes), ctx, 0, true},
+		{"or none", Or[int](no, no), ctx, 0, false},
+		{"or empty", Or[int](), ctx, 0, false},
+		{"not", Not[int](yes), ctx, 0, false},
+		{"input matches", InputMatches(func(in int) bool { return in > 3 }), ctx, 4, true},
+		{"input does not match", InputMatches(func(in int) bool { return in > 3 }), ctx, 3, false},
+		{"from context", FromContext[int](IsLoggingDisabled), DisableLogging(ctx), 0, true},
+		{"caller allowed", CallerIn[int]("batch", "admin"), WithCaller(ctx, "admin"), 0, true},
+		{"caller not allowed", CallerIn[int]("batch", "admin"), WithCaller(ctx, "web"), 0, false},
+		{"no caller", CallerIn[int]("batch"), ctx, 0, false},
+		{"never", Percentage[int](0), ctx, 0, false},
+		{"always", Percentage[int](100), ctx, 0, true},
+		{"composed", And(CallerIn[int]("batch"), Not(InputMatches(func(in int) bool { return in == 0 }))), WithCaller(ctx, "batch"), 1, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.cond(tc.ctx, tc.in))
+		})
+	}
+
+	half := Percentage[int](50)
+	var hits int
+	for range 10000 {
+		if half(ctx, 0) {
+			hits++
+		}
+	}
+	assert.InDelta(t, 5000, hits, 500)
+}
+
+func TestGateOn(t *testing.T) {
+	base := func(ctx context.Context, in int) (OutputWithMeta[[]string], error) {
+		return OutputWithMeta[[]string]{Data: []string{"base"}}, nil
+	}
+	op := GateOn(tag("mw"), InputMatches(func(in int) bool { return in > 0 }))(base)
+
+	out, _ := op(context.Background(), 1)
+	assert.Equal(t, []string{"base", "mw"}, out.Data)
+	out, _ = op(context.Background(), 0)
+	assert.Equal(t, []string{"base"}, out.Data)
+
+	out, _ = GateOn[int](tag("mw"), nil)(base)(context.Background(), 0)
+	assert.Equal(t, []string{"base", "mw"}, out.Data, "a nil condition always applies")
+}
+
+func TestGateAfter(t *testing.T) {
+	errDown := errors.New("down")
+	var calls int
+	base := func(ctx context.Context, in int) (OutputWithMeta[[]string], error) {
+		calls++
+		if in < 0 {
+			return OutputWithMeta[[]string]{}, errDown
+		}
+		return OutputWithMeta[[]string]{Data: []string{"base"}}, nil
+	}
+	cond := AnyOutcome(SlowerThan[int, []string](time.Second), OnError[int, []string]())
+	// Retry re-invokes next on errors: GateAfter must replay the outcome, not call base again.
+	mw := func(next RepoOp[int, []string]) RepoOp[int, []string] {
+		return tag("mw")(Retry[int, []string](2, 0)(next))
+	}
+
+	cases := []struct {
+		name    string
+		step    time.Duration
+		in      int
+		want    []string
+		wantErr error
+	}{
+		{"fast call skips mw", 10 * time.Millisecond, 1, []string{"base"}, nil},
+		{"slow call runs mw", 2 * time.Second, 1, []string{"base", "mw"}, nil},
+		{"failed call runs mw", 10 * time.Millisecond, -1, []string{"mw"}, errDown},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClock(t, at(12, 0), tc.step)
+			calls = 0
+			out, err := GateAfter(mw, cond)(base)(context.Background(), tc.in)
+			assert.Equal(t, tc.wantErr, err)
+			assert.Equal(t, tc.want, out.Data)
+			assert.Equal(t, 1, calls, "the repository is called once")
+		})
+	}
+}
+
+func TestMiddlewareBuilderDescribesConditions(t *testing.T) {
+	b := &MiddlewareBuilder[int, []string]{}
+	b.AddGate(MW_LOGGING, tag("log"), IsLoggingDisabled)
+	b.AddWhen(MW_TIMER, tag("timer"), CallerIn[int]("batch"))
+	b.AddAfter(MW_OUTPUT_RESULT, tag("out"), SlowerThan[int, []string](time.Second))
+	b.AddNamed(MW_RETRY, tag("retry"))
+
+	d := b.Describe()
+	require.Len(t, d.Entries, 4)
+	assert.Equal(t, EntryDescription{Name: MW_TIMER, Condition: "CallerIn", GateKind: GATE_WHEN}, d.Entries[1])
+	assert.Equal(t, EntryDescription{Name: MW_OUTPUT_RESULT, Condition: "SlowerThan", GateKind: GATE_AFTER}, d.Entries[2])
+	assert.Equal(t, "Logging [unless IsLoggingDisabled] -> Timer [when CallerIn] -> OutputResult [after SlowerThan] -> Retry -> base", d.String())
+}
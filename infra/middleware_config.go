@@ -14,6 +14,9 @@ type MiddlewareConfig struct {
 	MaskingCallback func(output any) any
 	RetryCallback   func(attempt any, err error)
 	LoggerCallback  func(ctx context.Context, msg string)
+
+	FallbackOp         RepoOp[any, any]
+	FallbackClassifier func(err error) bool
 }
 
 func BuildMiddlewarechain(config MiddlewareConfig) []Middleware[any, any] {
@@ -27,6 +30,10 @@ func BuildMiddlewarechain(config MiddlewareConfig) []Middleware[any, any] {
 	if config.MaskingCallback != nil {
 		chain = append(chain, MaskOutput[any](config.MaskingCallback))
 	}
+	// Fallback sits outside Retry so it only kicks in once retries are exhausted.
+	if config.FallbackOp != nil {
+		chain = append(chain, Fallback(config.FallbackClassifier, config.FallbackOp))
+	}
 	if config.RetryCount > 0 {
 		chain = append(chain, Retry[any, any](config.RetryCount, config.RetryDelay))
 	}
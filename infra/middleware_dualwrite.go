@@ -0,0 +1,270 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	SECONDARY_WRITE = "secondary_write"
+)
+
+// Outcomes of the secondary write recorded under SECONDARY_WRITE.
+const (
+	SECONDARY_WRITE_OK     = "ok"
+	SECONDARY_WRITE_FAILED = "failed"
+	SECONDARY_WRITE_QUEUED = "queued"
+)
+
+var (
+	ErrSecondaryWrite = errors.New("secondary write failed")
+	// ErrQueuedBehind is the LastErr of a write queued without being attempted because
+	// earlier writes for its key were still queued.
+	ErrQueuedBehind = errors.New("queued behind earlier writes")
+)
+
+// SecondaryFailureMode decides what happens when the secondary write fails. The
+// primary is authoritative: its result is always returned and a failed primary write
+// is never sent to the secondary.
+type SecondaryFailureMode int
+
+const (
+	// SecondaryLog logs the failure and reports success.
+	SecondaryLog SecondaryFailureMode = iota
+	// SecondaryQueue queues the write on the DualWriteQueue for a later retry.
+	SecondaryQueue
+	// SecondaryFail returns ErrSecondaryWrite. The primary write is not rolled back.
+	SecondaryFail
+)
+
+type DualWriteConfig[In any, Out any] struct {
+	Operation string
+	Mode      SecondaryFailureMode
+	// Key returns the ID of the record written, used in the reconciliation report.
+	Key func(input In) string
+	// Tracker records divergent IDs. Optional.
+	Tracker *DivergenceTracker
+	// Queue receives failed writes in SecondaryQueue mode.
+	Queue  *DualWriteQueue[In, Out]
+	Logger func(ctx context.Context, msg string)
+}
+
+// DualWrite sends every successful write to secondary as well, for write-path migrations.
+// In SecondaryQueue mode, writes for a key with queued writes are queued behind them
+// instead of being sent, so the secondary applies the writes of each key in order.
+func DualWrite[In any, Out any](secondary RepoOp[In, Out], cfg DualWriteConfig[In, Out]) Middleware[In, Out] {
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			out, err := next(ctx, input)
+			if err != nil || IsDualWriteDisabled(ctx) {
+				return out, err
+			}
+			if out.Meta == nil {
+				out.Meta = make(map[string]interface{})
+			}
+
+			key := ""
+			if cfg.Key != nil {
+				key = cfg.Key(input)
+			}
+			queue := cfg.Mode == SecondaryQueue && cfg.Queue != nil
+			var secErr error
+			if queue && cfg.Queue.pushBehind(key, cfg.Operation, input) {
+				secErr = ErrQueuedBehind
+			} else if _, secErr = secondary(ctx, input); secErr == nil {
+				out.Meta[SECONDARY_WRITE] = SECONDARY_WRITE_OK
+				return out, nil
+			} else if queue {
+				// Queue before recording, so a concurrent Retry draining the key cannot
+				// resolve the divergence recorded for this write.
+				cfg.Queue.push(QueuedWrite[In]{Key: key, Operation: cfg.Operation, Input: input, Attempts: 1, LastErr: secErr})
+			}
+
+			if cfg.Tracker != nil {
+				cfg.Tracker.Record(key, cfg.Operation, secErr)
+			}
+			if cfg.Logger != nil {
+				cfg.Logger(ctx, fmt.Sprintf("[DUAL WRITE] %s %s: secondary failed: %v", cfg.Operation, key, secErr))
+			}
+
+			switch {
+			case queue:
+				out.Meta[SECONDARY_WRITE] = SECONDARY_WRITE_QUEUED
+			case cfg.Mode == SecondaryFail:
+				out.Meta[SECONDARY_WRITE] = SECONDARY_WRITE_FAILED
+				return out, fmt.Errorf("%w: %s %s: %w", ErrSecondaryWrite, cfg.Operation, key, secErr)
+			default:
+				out.Meta[SECONDARY_WRITE] = SECONDARY_WRITE_FAILED
+			}
+			return out, nil
+		}
+	}
+}
+
+// DualWriteQueue holds secondary writes waiting to be retried.
+type DualWriteQueue[In any, Out any] struct {
+	mu        sync.Mutex
+	secondary RepoOp[In, Out]
+	tracker   *DivergenceTracker
+	pending   []QueuedWrite[In]
+	// queued counts the writes of each key that are pending or being retried.
+	queued map[string]int
+}
+
+type QueuedWrite[In any] struct {
+	Key       string
+	Operation string
+	Input     In
+	Attempts  int
+	LastErr   error
+}
+
+// NewDualWriteQueue returns a queue retrying against secondary. Keys are resolved in
+// tracker, which may be nil, once all their queued writes succeeded.
+func NewDualWriteQueue[In any, Out any](secondary RepoOp[In, Out], tracker *DivergenceTracker) *DualWriteQueue[In, Out] {
+	return &DualWriteQueue[In, Out]{secondary: secondary, tracker: tracker, queued: make(map[string]int)}
+}
+
+func (q *DualWriteQueue[In, Out]) push(w QueuedWrite[In]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, w)
+	q.queued[w.Key]++
+}
+
+// pushBehind queues a write without attempting it when key has queued writes, and
+// reports whether it did.
+func (q *DualWriteQueue[In, Out]) pushBehind(key, operation string, input In) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queued[key] == 0 {
+		return false
+	}
+	q.pending = append(q.pending, QueuedWrite[In]{Key: key, Operation: operation, Input: input, LastErr: ErrQueuedBehind})
+	q.queued[key]++
+	return true
+}
+
+// done marks a queued write of key as applied, resolving key once none is left.
+func (q *DualWriteQueue[In, Out]) done(key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queued[key]--; q.queued[key] > 0 {
+		return
+	}
+	delete(q.queued, key)
+	if q.tracker != nil {
+		q.tracker.Resolve(key)
+	}
+}
+
+// Len returns the number of queued writes.
+func (q *DualWriteQueue[In, Out]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)
+}
+
+// Retry replays the queued writes in order, keeping the ones that fail again and
+// recording them in the tracker. Once a write of a key fails, the later writes of that
+// key stay queued without being attempted.
+func (q *DualWriteQueue[In, Out]) Retry(ctx context.Context) (succeeded, failed int) {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	var remaining []QueuedWrite[In]
+	blocked := make(map[string]bool)
+	for _, w := range pending {
+		if ctx.Err() != nil || blocked[w.Key] {
+			remaining = append(remaining, w)
+			continue
+		}
+		if _, err := q.secondary(ctx, w.Input); err != nil {
+			w.Attempts++
+			w.LastErr = err
+			remaining = append(remaining, w)
+			blocked[w.Key] = true
+			if q.tracker != nil {
+				q.tracker.Record(w.Key, w.Operation, err)
+			}
+			continue
+		}
+		succeeded++
+		q.done(w.Key)
+	}
+
+	q.mu.Lock()
+	q.pending = append(remaining, q.pending...)
+	q.mu.Unlock()
+	return succeeded, len(remaining)
+}
+
+// Divergence is a record whose secondary copy may differ from the primary.
+type Divergence struct {
+	ID         string
+	Operations []string
+	LastError  string
+	FirstSeen  time.Time
+	LastSeen   time.Time
+}
+
+// DivergenceTracker collects the IDs whose secondary writes failed. It is safe for
+// concurrent use.
+type DivergenceTracker struct {
+	mu      sync.Mutex
+	entries map[string]*Divergence
+}
+
+func NewDivergenceTracker() *DivergenceTracker {
+	return &DivergenceTracker{entries: make(map[string]*Divergence)}
+}
+
+// Record notes that the secondary write of operation for id failed with err.
+func (t *DivergenceTracker) Record(id, operation string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	at := time.Now().UTC()
+	d, ok := t.entries[id]
+	if !ok {
+		d = &Divergence{ID: id, FirstSeen: at}
+		t.entries[id] = d
+	}
+	d.Operations = append(d.Operations, operation)
+	d.LastError = err.Error()
+	d.LastSeen = at
+}
+
+// Resolve forgets id, e.g. once the secondary caught up.
+func (t *DivergenceTracker) Resolve(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, id)
+}
+
+// ReconciliationReport lists the records that need reconciling, sorted by ID.
+type ReconciliationReport struct {
+	GeneratedAt  time.Time
+	DivergentIDs []string
+	Divergences  []Divergence
+}
+
+func (t *DivergenceTracker) Report() ReconciliationReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := ReconciliationReport{GeneratedAt: time.Now().UTC()}
+	for id, d := range t.entries {
+		r.DivergentIDs = append(r.DivergentIDs, id)
+		c := *d
+		c.Operations = append([]string(nil), d.Operations...)
+		r.Divergences = append(r.Divergences, c)
+	}
+	sort.Strings(r.DivergentIDs)
+	sort.Slice(r.Divergences, func(i, j int) bool { return r.Divergences[i].ID < r.Divergences[j].ID })
+	return r
+}
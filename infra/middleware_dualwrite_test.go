@@ -0,0 +1,173 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDualWriteFailureModes(t *testing.T) {
+	errSecondary := errors.New("secondary down")
+	var primaryCalls, secondaryCalls int
+	failing := true
+	primary := func(ctx context.Context, id string) (OutputWithMeta[struct{}], error) {
+		primaryCalls++
+		if id == "bad" {
+			return OutputWithMeta[struct{}]{}, errors.New("primary rejected")
+		}
+		return OutputWithMeta[struct{}]{}, nil
+	}
+	secondary := func(ctx context.Context, id string) (OutputWithMeta[struct{}], error) {
+		secondaryCalls++
+		if failing {
+			return OutputWithMeta[struct{}]{}, errSecondary
+		}
+		return OutputWithMeta[struct{}]{}, nil
+	}
+	key := func(id string) string { return id }
+
+	t.Run("PrimaryFailureSkipsSecondary", func(t *testing.T) {
+		secondaryCalls = 0
+		op := DualWrite(secondary, DualWriteConfig[string, struct{}]{Key: key})(primary)
+		_, err := op(context.Background(), "bad")
+		assert.EqualError(t, err, "primary rejected")
+		assert.Zero(t, secondaryCalls)
+	})
+
+	t.Run("Log", func(t *testing.T) {
+		tracker := NewDivergenceTracker()
+		var logged []string
+		op := DualWrite(secondary, DualWriteConfig[string, struct{}]{
+			Operation: "Insert",
+			Key:       key,
+			Tracker:   tracker,
+			Logger:    func(ctx context.Context, msg string) { logged = append(logged, msg) },
+		})(primary)
+
+		out, err := op(context.Background(), "r1")
+		assert.NoError(t, err)
+		assert.Equal(t, SECONDARY_WRITE_FAILED, out.Meta[SECONDARY_WRITE])
+		assert.Len(t, logged, 1)
+		assert.Equal(t, []string{"r1"}, tracker.Report().DivergentIDs)
+	})
+
+	t.Run("Fail", func(t *testing.T) {
+		before := primaryCalls
+		op := DualWrite(secondary, DualWriteConfig[string, struct{}]{Mode: SecondaryFail, Key: key})(primary)
+		_, err := op(context.Background(), "r1")
+		assert.ErrorIs(t, err, ErrSecondaryWrite)
+		assert.ErrorIs(t, err, errSecondary)
+		assert.Equal(t, before+1, primaryCalls)
+	})
+
+	t.Run("QueueAndRetry", func(t *testing.T) {
+		tracker := NewDivergenceTracker()
+		queue := NewDualWriteQueue(secondary, tracker)
+		op := DualWrite(secondary, DualWriteConfig[string, struct{}]{
+			Operation: "Update",
+			Mode:      SecondaryQueue,
+			Key:       key,
+			Tracker:   tracker,
+			Queue:     queue,
+		})(primary)
+
+		for _, id := range []string{"r2", "r1", "r2"} {
+			out, err := op(context.Background(), id)
+			require.NoError(t, err)
+			assert.Equal(t, SECONDARY_WRITE_QUEUED, out.Meta[SECONDARY_WRITE])
+		}
+		report := tracker.Report()
+		assert.Equal(t, []string{"r1", "r2"}, report.DivergentIDs)
+		assert.Equal(t, []string{"Update", "Update"}, report.Divergences[1].Operations)
+
+		ok, failed := queue.Retry(context.Background())
+		assert.Equal(t, 0, ok)
+		assert.Equal(t, 3, failed)
+
+		failing = false
+		defer func() { failing = true }()
+		ok, failed = queue.Retry(context.Background())
+		assert.Equal(t, 3, ok)
+		assert.Equal(t, 0, failed)
+		assert.Zero(t, queue.Len())
+		assert.Empty(t, tracker.Report().DivergentIDs)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		secondaryCalls = 0
+		op := DualWrite(secondary, DualWriteConfig[string, struct{}]{Mode: SecondaryFail, Key: key})(primary)
+		_, err := op(DisableDualWrite(context.Background()), "r1")
+		assert.NoError(t, err)
+		assert.Zero(t, secondaryCalls)
+	})
+}
+
+func TestDualWriteQueueKeepsKeyOrder(t *testing.T) {
+	errSecondary := errors.New("secondary down")
+	failing := map[string]bool{}
+	var attempted, applied []string
+	primary := func(ctx context.Context, write string) (OutputWithMeta[struct{}], error) {
+		return OutputWithMeta[struct{}]{}, nil
+	}
+	secondary := func(ctx context.Context, write string) (OutputWithMeta[struct{}], error) {
+		attempted = append(attempted, write)
+		if failing[write] {
+			return OutputWithMeta[struct{}]{}, errSecondary
+		}
+		applied = append(applied, write)
+		return OutputWithMeta[struct{}]{}, nil
+	}
+	tracker := NewDivergenceTracker()
+	queue := NewDualWriteQueue(secondary, tracker)
+	op := DualWrite(secondary, DualWriteConfig[string, struct{}]{
+		Operation: "Update",
+		Mode:      SecondaryQueue,
+		Key:       func(write string) string { key, _, _ := strings.Cut(write, ":"); return key },
+		Tracker:   tracker,
+		Queue:     queue,
+	})(primary)
+	ctx := context.Background()
+
+	failing["r1:1"] = true
+	for _, write := range []string{"r1:1", "r1:2", "r2:1"} {
+		out, err := op(ctx, write)
+		require.NoError(t, err)
+		want := SECONDARY_WRITE_QUEUED
+		if write == "r2:1" {
+			want = SECONDARY_WRITE_OK
+		}
+		assert.Equal(t, want, out.Meta[SECONDARY_WRITE], write)
+	}
+	assert.Equal(t, []string{"r1:1", "r2:1"}, attempted, "r1:2 is queued behind r1:1 without being sent")
+	assert.Equal(t, 2, queue.Len())
+
+	ok, failed := queue.Retry(ctx)
+	assert.Equal(t, 0, ok)
+	assert.Equal(t, 2, failed)
+	assert.Equal(t, []string{"r1:1", "r2:1", "r1:1"}, attempted, "r1:2 waits while r1:1 fails")
+	report := tracker.Report()
+	assert.Equal(t, []string{"r1"}, report.DivergentIDs)
+	assert.Equal(t, []string{"Update", "Update", "Update"}, report.Divergences[0].Operations, "the failed retry is recorded")
+	assert.Equal(t, errSecondary.Error(), report.Divergences[0].LastError)
+
+	failing["r1:1"], failing["r1:2"] = false, true
+	ok, failed = queue.Retry(ctx)
+	assert.Equal(t, 1, ok)
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, []string{"r1"}, tracker.Report().DivergentIDs, "r1 stays divergent while r1:2 is queued")
+
+	failing["r1:2"] = false
+	ok, failed = queue.Retry(ctx)
+	assert.Equal(t, 1, ok)
+	assert.Equal(t, 0, failed)
+	assert.Empty(t, tracker.Report().DivergentIDs)
+	assert.Equal(t, []string{"r2:1", "r1:1", "r1:2"}, applied, "the writes of each key are applied in order")
+
+	_, err := op(ctx, "r1:3")
+	require.NoError(t, err)
+	assert.Equal(t, "r1:3", attempted[len(attempted)-1], "writes are sent again once the key is drained")
+}
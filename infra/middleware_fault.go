@@ -0,0 +1,185 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	FAULT_INJECTED = "fault_injected"
+)
+
+// Kinds of fault recorded under FAULT_INJECTED.
+const (
+	FAULT_LATENCY = "latency"
+	FAULT_ERROR   = "error"
+	FAULT_EMPTY   = "empty"
+	FAULT_PARTIAL = "partial"
+)
+
+var ErrInjectedFault = errors.New("injected fault")
+
+// LatencyDistribution draws an injected delay.
+type LatencyDistribution func(r *rand.Rand) time.Duration
+
+// FixedLatency always delays by d.
+func FixedLatency(d time.Duration) LatencyDistribution {
+	return func(r *rand.Rand) time.Duration { return d }
+}
+
+// UniformLatency delays by a duration drawn uniformly from [lo, hi).
+func UniformLatency(lo, hi time.Duration) LatencyDistribution {
+	return func(r *rand.Rand) time.Duration {
+		if hi <= lo {
+			return lo
+		}
+		return lo + time.Duration(r.Int64N(int64(hi-lo)))
+	}
+}
+
+// NormalLatency delays by a normally distributed duration, never below zero.
+func NormalLatency(mean, stddev time.Duration) LatencyDistribution {
+	return func(r *rand.Rand) time.Duration {
+		return max(0, time.Duration(r.NormFloat64()*float64(stddev))+mean)
+	}
+}
+
+// FaultConfig describes the faults to inject. Rates are probabilities between 0 and 1
+// evaluated independently on every call: latency is added first, then a panic, an
+// error (next is not called) or an empty/partial result of the real call.
+type FaultConfig[Out any] struct {
+	LatencyRate float64
+	Latency     LatencyDistribution
+
+	PanicRate float64
+
+	ErrorRate float64
+	Err       error // defaults to ErrInjectedFault
+
+	EmptyRate float64
+
+	PartialRate float64
+	Partial     func(out Out, r *rand.Rand) Out
+
+	// Seed makes the injected faults reproducible. Zero picks a random seed.
+	Seed uint64
+}
+
+// PartialSlice keeps a random prefix of out, shorter than out itself.
+func PartialSlice[T any](out []T, r *rand.Rand) []T {
+	if len(out) == 0 {
+		return out
+	}
+	return out[:r.IntN(len(out))]
+}
+
+type faultKey struct{}
+
+// WithFaultConfig overrides the faults injected by the FaultInjection middleware
+// registered under name for this request. Out must match the middleware's output type.
+func WithFaultConfig[Out any](ctx context.Context, name string, cfg FaultConfig[Out]) context.Context {
+	configs := map[string]any{}
+	if prev, ok := ctx.Value(faultKey{}).(map[string]any); ok {
+		for k, v := range prev {
+			configs[k] = v
+		}
+	}
+	configs[name] = cfg
+	return context.WithValue(ctx, faultKey{}, configs)
+}
+
+func faultConfigFrom[Out any](ctx context.Context, name string) (FaultConfig[Out], bool) {
+	configs, _ := ctx.Value(faultKey{}).(map[string]any)
+	cfg, ok := configs[name].(FaultConfig[Out])
+	return cfg, ok
+}
+
+// FaultInjection injects failures into the operation named name, for chaos testing
+// retry and fallback configuration without touching the store. The configuration can
+// be replaced per request with WithFaultConfig and switched off with DisableFaults.
+func FaultInjection[In any, Out any](name string, cfg FaultConfig[Out]) Middleware[In, Out] {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(seed, seed))
+	roll := func(rate float64) bool {
+		if rate <= 0 {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.Float64() < rate
+	}
+	locked := func(fn func(r *rand.Rand)) {
+		mu.Lock()
+		defer mu.Unlock()
+		fn(rng)
+	}
+
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsFaultInjectionDisabled(ctx) {
+				return next(ctx, input)
+			}
+			cfg := cfg
+			if override, ok := faultConfigFrom[Out](ctx, name); ok {
+				cfg = override
+			}
+			var injected []string
+
+			if cfg.Latency != nil && roll(cfg.LatencyRate) {
+				var d time.Duration
+				locked(func(r *rand.Rand) { d = cfg.Latency(r) })
+				injected = append(injected, FAULT_LATENCY)
+				select {
+				case <-time.After(d):
+				case <-ctx.Done():
+					return withFaults(OutputWithMeta[Out]{}, injected), ctx.Err()
+				}
+			}
+
+			if roll(cfg.PanicRate) {
+				panic(fmt.Errorf("%w: panic in %s", ErrInjectedFault, name))
+			}
+
+			if roll(cfg.ErrorRate) {
+				err := cfg.Err
+				if err == nil {
+					err = ErrInjectedFault
+				}
+				return withFaults(OutputWithMeta[Out]{}, append(injected, FAULT_ERROR)), err
+			}
+
+			out, err := next(ctx, input)
+			if err == nil {
+				switch {
+				case roll(cfg.EmptyRate):
+					var zero Out
+					out.Data = zero
+					injected = append(injected, FAULT_EMPTY)
+				case cfg.Partial != nil && roll(cfg.PartialRate):
+					locked(func(r *rand.Rand) { out.Data = cfg.Partial(out.Data, r) })
+					injected = append(injected, FAULT_PARTIAL)
+				}
+			}
+			return withFaults(out, injected), err
+		}
+	}
+}
+
+func withFaults[Out any](out OutputWithMeta[Out], injected []string) OutputWithMeta[Out] {
+	if len(injected) == 0 {
+		return out
+	}
+	if out.Meta == nil {
+		out.Meta = make(map[string]interface{})
+	}
+	out.Meta[FAULT_INJECTED] = injected
+	return out
+}
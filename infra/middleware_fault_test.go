@@ -0,0 +1,60 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFaultInjectionIsReproducible(t *testing.T) {
+	base := func(ctx context.Context, in int) (OutputWithMeta[[]int], error) {
+		return OutputWithMeta[[]int]{Data: []int{1, 2, 3, 4}}, nil
+	}
+	cfg := FaultConfig[[]int]{ErrorRate: 0.3, PartialRate: 0.5, Partial: PartialSlice[int], Seed: 42}
+
+	run := func() []string {
+		op := FaultInjection[int]("find", cfg)(base)
+		var outcomes []string
+		for range 50 {
+			out, err := op(context.Background(), 0)
+			switch {
+			case errors.Is(err, ErrInjectedFault):
+				outcomes = append(outcomes, "error")
+			default:
+				outcomes = append(outcomes, string(rune('0'+len(out.Data))))
+			}
+		}
+		return outcomes
+	}
+
+	first := run()
+	assert.Equal(t, first, run())
+	assert.Contains(t, first, "error")
+	assert.Contains(t, first, "4")
+}
+
+func TestFaultInjectionContextOverrides(t *testing.T) {
+	base := func(ctx context.Context, in int) (OutputWithMeta[[]int], error) {
+		return OutputWithMeta[[]int]{Data: []int{1}}, nil
+	}
+	op := FaultInjection[int]("find", FaultConfig[[]int]{ErrorRate: 1})(base)
+
+	_, err := op(context.Background(), 0)
+	assert.ErrorIs(t, err, ErrInjectedFault)
+
+	_, err = op(DisableFaults(context.Background()), 0)
+	assert.NoError(t, err)
+
+	ctx := WithFaultConfig(context.Background(), "find", FaultConfig[[]int]{EmptyRate: 1, LatencyRate: 1, Latency: FixedLatency(time.Millisecond)})
+	out, err := op(ctx, 0)
+	assert.NoError(t, err)
+	assert.Nil(t, out.Data)
+	assert.Equal(t, []string{FAULT_LATENCY, FAULT_EMPTY}, out.Meta[FAULT_INJECTED])
+
+	assert.PanicsWithError(t, "injected fault: panic in find", func() {
+		_, _ = op(WithFaultConfig(context.Background(), "find", FaultConfig[[]int]{PanicRate: 1}), 0)
+	})
+}
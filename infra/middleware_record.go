@@ -0,0 +1,270 @@
+package infra
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	REPLAYED = "replayed"
+)
+
+var ErrNoRecording = errors.New("no recording matches input")
+
+// Codec converts operation inputs and outputs to and from their cassette form.
+type Codec[T any] interface {
+	Encode(v T) (json.RawMessage, error)
+	Decode(raw json.RawMessage) (T, error)
+}
+
+type jsonCodec[T any] struct{}
+
+// JSONCodec encodes values with encoding/json.
+func JSONCodec[T any]() Codec[T] {
+	return jsonCodec[T]{}
+}
+
+func (jsonCodec[T]) Encode(v T) (json.RawMessage, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec[T]) Decode(raw json.RawMessage) (T, error) {
+	var v T
+	err := json.Unmarshal(raw, &v)
+	return v, err
+}
+
+// CassetteEntry is one recorded call, stored as a single JSON line.
+type CassetteEntry struct {
+	Operation  string                 `json:"operation"`
+	Input      json.RawMessage        `json:"input"`
+	Output     json.RawMessage        `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Meta       map[string]interface{} `json:"meta,omitempty"`
+	RecordedAt time.Time              `json:"recorded_at"`
+}
+
+// CassetteWriter appends entries to a JSON-lines cassette. It is safe for concurrent use.
+// Recording never fails the operation; encoding or write errors go to OnError.
+type CassetteWriter struct {
+	mu      sync.Mutex
+	enc     *json.Encoder
+	OnError func(err error)
+}
+
+func NewCassetteWriter(w io.Writer) *CassetteWriter {
+	return &CassetteWriter{enc: json.NewEncoder(w)}
+}
+
+func (c *CassetteWriter) write(e CassetteEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(e); err != nil {
+		c.fail(err)
+	}
+}
+
+func (c *CassetteWriter) fail(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// Record writes the input, output, error and meta of every call of operation to w.
+func Record[In any, Out any](w *CassetteWriter, operation string, inCodec Codec[In], outCodec Codec[Out]) Middleware[In, Out] {
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			out, err := next(ctx, input)
+
+			entry := CassetteEntry{Operation: operation, Meta: encodeMeta(out.Meta), RecordedAt: time.Now().UTC()}
+			var encErr error
+			if entry.Input, encErr = inCodec.Encode(input); encErr != nil {
+				w.fail(fmt.Errorf("record %s input: %w", operation, encErr))
+				return out, err
+			}
+			if err != nil {
+				entry.Error = err.Error()
+			} else if entry.Output, encErr = outCodec.Encode(out.Data); encErr != nil {
+				w.fail(fmt.Errorf("record %s output: %w", operation, encErr))
+				return out, err
+			}
+			w.write(entry)
+			return out, err
+		}
+	}
+}
+
+// encodeMeta makes meta values JSON friendly: errors and durations become strings.
+func encodeMeta(meta map[string]interface{}) map[string]interface{} {
+	if len(meta) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(meta))
+	for k, v := range meta {
+		switch val := v.(type) {
+		case error:
+			out[k] = val.Error()
+		case time.Duration:
+			out[k] = val.String()
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Cassette holds recorded entries for replay. It is safe for concurrent use.
+type Cassette struct {
+	mu      sync.Mutex
+	entries []CassetteEntry
+	used    []bool
+}
+
+// LoadCassette reads a JSON-lines cassette.
+func LoadCassette(r io.Reader) (*Cassette, error) {
+	c := &Cassette{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var e CassetteEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("cassette line %d: %w", line, err)
+		}
+		c.entries = append(c.entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	c.used = make([]bool, len(c.entries))
+	return c, nil
+}
+
+// LoadCassetteFile reads a JSON-lines cassette file.
+func LoadCassetteFile(path string) (*Cassette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadCassette(f)
+}
+
+// take returns the first unused entry accepted by match, falling back to the last
+// used one so repeated calls keep being answered.
+func (c *Cassette) take(operation string, match func(e CassetteEntry) bool) (CassetteEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	last := -1
+	for i, e := range c.entries {
+		if e.Operation != operation || !match(e) {
+			continue
+		}
+		if !c.used[i] {
+			c.used[i] = true
+			return e, true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return c.entries[last], true
+	}
+	return CassetteEntry{}, false
+}
+
+// InputMatcher reports whether a recorded input answers the actual one.
+type InputMatcher[In any] func(recorded In, actual In) bool
+
+type ReplayConfig[In any, Out any] struct {
+	Operation string
+	InCodec   Codec[In]
+	OutCodec  Codec[Out]
+
+	// Match selects the recording for an input. Nil matches on the exact encoded input.
+	Match InputMatcher[In]
+	// ErrorFor rebuilds recorded errors, e.g. to map "not found" back to ErrNotFound.
+	// Nil returns errors.New(msg).
+	ErrorFor func(msg string) error
+}
+
+// Replay answers calls from the cassette and only calls next when nothing matches.
+func Replay[In any, Out any](c *Cassette, cfg ReplayConfig[In, Out]) Middleware[In, Out] {
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			match, err := replayMatcher(cfg, input)
+			if err != nil {
+				return OutputWithMeta[Out]{}, err
+			}
+			entry, ok := c.take(cfg.Operation, match)
+			if !ok {
+				return next(ctx, input)
+			}
+
+			// The entry is shared by every call it answers, so each gets its own Meta
+			// for the middlewares above to write to.
+			out := OutputWithMeta[Out]{Meta: make(map[string]interface{}, len(entry.Meta)+1)}
+			maps.Copy(out.Meta, entry.Meta)
+			out.Meta[REPLAYED] = true
+			if entry.Error != "" {
+				if cfg.ErrorFor != nil {
+					return out, cfg.ErrorFor(entry.Error)
+				}
+				return out, errors.New(entry.Error)
+			}
+			if out.Data, err = cfg.OutCodec.Decode(entry.Output); err != nil {
+				return out, fmt.Errorf("replay %s output: %w", cfg.Operation, err)
+			}
+			return out, nil
+		}
+	}
+}
+
+// ReplayOp is a fake base operation served from the cassette; unmatched inputs fail
+// with ErrNoRecording.
+func ReplayOp[In any, Out any](c *Cassette, cfg ReplayConfig[In, Out]) RepoOp[In, Out] {
+	return Replay(c, cfg)(func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+		return OutputWithMeta[Out]{}, fmt.Errorf("%w: %s %+v", ErrNoRecording, cfg.Operation, input)
+	})
+}
+
+func replayMatcher[In any, Out any](cfg ReplayConfig[In, Out], input In) (func(e CassetteEntry) bool, error) {
+	if cfg.Match != nil {
+		return func(e CassetteEntry) bool {
+			recorded, err := cfg.InCodec.Decode(e.Input)
+			return err == nil && cfg.Match(recorded, input)
+		}, nil
+	}
+	raw, err := cfg.InCodec.Encode(input)
+	if err != nil {
+		return nil, fmt.Errorf("replay %s input: %w", cfg.Operation, err)
+	}
+	want, err := compactJSON(raw)
+	if err != nil {
+		return nil, err
+	}
+	return func(e CassetteEntry) bool {
+		got, err := compactJSON(e.Input)
+		return err == nil && bytes.Equal(got, want)
+	}, nil
+}
+
+func compactJSON(raw json.RawMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package infra
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errMissing = errors.New("missing")
+
+func TestRecordThenReplay(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCassetteWriter(&buf)
+	base := func(ctx context.Context, name string) (OutputWithMeta[[]string], error) {
+		if name == "none" {
+			return OutputWithMeta[[]string]{}, errMissing
+		}
+		return OutputWithMeta[[]string]{Data: []string{name, name + "!"}}, nil
+	}
+	op := Chain(base, Record(w, "find", JSONCodec[string](), JSONCodec[[]string]()), Timer[string, []string]())
+	_, _ = op(context.Background(), "pho")
+	_, _ = op(context.Background(), "none")
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	cassette, err := LoadCassette(&buf)
+	require.NoError(t, err)
+	replay := ReplayOp(cassette, ReplayConfig[string, []string]{
+		Operation: "find",
+		InCodec:   JSONCodec[string](),
+		OutCodec:  JSONCodec[[]string](),
+		ErrorFor: func(msg string) error {
+			if msg == errMissing.Error() {
+				return errMissing
+			}
+			return errors.New(msg)
+		},
+	})
+
+	out, err := replay(context.Background(), "pho")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pho", "pho!"}, out.Data)
+	assert.Equal(t, true, out.Meta[REPLAYED])
+	assert.Contains(t, out.Meta, DURATION)
+
+	_, err = replay(context.Background(), "none")
+	assert.ErrorIs(t, err, errMissing)
+
+	_, err = replay(context.Background(), "ramen")
+	assert.ErrorIs(t, err, ErrNoRecording)
+}
+
+func TestReplayCustomMatcher(t *testing.T) {
+	cassette, err := LoadCassette(strings.NewReader(`{"operation":"find","input":"PHO","output":["a"]}`))
+	require.NoError(t, err)
+	replay := ReplayOp(cassette, ReplayConfig[string, []string]{
+		Operation: "find",
+		InCodec:   JSONCodec[string](),
+		OutCodec:  JSONCodec[[]string](),
+		Match:     strings.EqualFold,
+	})
+
+	out, err := replay(context.Background(), "pho")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a"}, out.Data)
+}
+
+func TestReplayConcurrentCallsDoNotShareMeta(t *testing.T) {
+	cassette, err := LoadCassette(strings.NewReader(`{"operation":"find","input":"pho","output":["a"],"meta":{"region":"eu"}}`))
+	require.NoError(t, err)
+	cfg := ReplayConfig[string, []string]{
+		Operation: "find",
+		InCodec:   JSONCodec[string](),
+		OutCodec:  JSONCodec[[]string](),
+	}
+	timed := Chain(ReplayOp(cassette, cfg), Timer[string, []string]())
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out, err := timed(context.Background(), "pho")
+			assert.NoError(t, err)
+			assert.Equal(t, "eu", out.Meta["region"])
+			assert.Contains(t, out.Meta, DURATION)
+		}()
+	}
+	wg.Wait()
+
+	out, err := ReplayOp(cassette, cfg)(context.Background(), "pho")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"region": "eu", REPLAYED: true}, out.Meta,
+		"middlewares of earlier calls do not write to the recorded Meta")
+}
@@ -0,0 +1,139 @@
+package infra
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	SAMPLED       = "sampled"
+	SAMPLE_REASON = "sample_reason"
+)
+
+// Reasons recorded under SAMPLE_REASON.
+const (
+	SAMPLE_REASON_HEAD  = "head"
+	SAMPLE_REASON_ERROR = "error"
+	SAMPLE_REASON_SLOW  = "slow"
+)
+
+// Sampler makes the up-front sampling decision for a request.
+type Sampler func(ctx context.Context) bool
+
+// AlwaysSample samples every request.
+func AlwaysSample() Sampler {
+	return func(ctx context.Context) bool { return true }
+}
+
+// Probabilistic samples roughly rate (0-1) of requests.
+func Probabilistic(rate float64) Sampler {
+	return func(ctx context.Context) bool {
+		return rand.Float64() < rate
+	}
+}
+
+// ProbabilisticSeeded samples like Probabilistic but draws from a source seeded with
+// seed, so the decisions are reproducible.
+func ProbabilisticSeeded(rate float64, seed uint64) Sampler {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewPCG(seed, seed))
+	return func(ctx context.Context) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.Float64() < rate
+	}
+}
+
+// RatePerSecond samples at most n requests per second.
+func RatePerSecond(n int) Sampler {
+	var mu sync.Mutex
+	var window time.Time
+	count := 0
+	return func(ctx context.Context) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		t := now().Truncate(time.Second)
+		if !t.Equal(window) {
+			window, count = t, 0
+		}
+		if count >= n {
+			return false
+		}
+		count++
+		return true
+	}
+}
+
+// SamplingPolicy combines an up-front Head decision with outcome based rules: requests
+// that were not head-sampled are still sampled when they fail (OnError) or take longer
+// than SlowerThan. A nil Head never head-samples, so the zero policy samples nothing.
+type SamplingPolicy struct {
+	Head       Sampler
+	OnError    bool
+	SlowerThan time.Duration
+}
+
+type samplingKey struct{}
+
+// WithSamplingDecision records the head sampling decision on the context. Sampled
+// reuses a decision already present, so every sampled middleware of a request agrees.
+func WithSamplingDecision(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, samplingKey{}, sampled)
+}
+
+// SamplingDecision returns the head sampling decision recorded on the context.
+func SamplingDecision(ctx context.Context) (sampled bool, decided bool) {
+	sampled, decided = ctx.Value(samplingKey{}).(bool)
+	return sampled, decided
+}
+
+// Sampled runs mw only for sampled requests. The head decision is taken once per
+// request and propagated through the context; outcome based sampling runs mw around
+// a replay of the result, as GateAfter does. The decision is recorded in Meta.
+func Sampled[In any, Out any](policy SamplingPolicy, mw Middleware[In, Out]) Middleware[In, Out] {
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		wrapped := mw(next)
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			sampled, decided := SamplingDecision(ctx)
+			if !decided {
+				sampled = policy.Head != nil && policy.Head(ctx)
+				ctx = WithSamplingDecision(ctx, sampled)
+			}
+			if sampled {
+				out, err := wrapped(ctx, input)
+				return markSampled(out, SAMPLE_REASON_HEAD), err
+			}
+
+			start := now()
+			out, err := next(ctx, input)
+			elapsed := now().Sub(start)
+
+			reason := ""
+			switch {
+			case err != nil && policy.OnError:
+				reason = SAMPLE_REASON_ERROR
+			case policy.SlowerThan > 0 && elapsed > policy.SlowerThan:
+				reason = SAMPLE_REASON_SLOW
+			}
+			if reason == "" {
+				return markSampled(out, ""), err
+			}
+
+			out, err = mw(replay[In](out, err))(ctx, input)
+			return markSampled(out, reason), err
+		}
+	}
+}
+
+func markSampled[Out any](out OutputWithMeta[Out], reason string) OutputWithMeta[Out] {
+	if out.Meta == nil {
+		out.Meta = make(map[string]interface{})
+	}
+	out.Meta[SAMPLED] = reason != ""
+	if reason != "" {
+		out.Meta[SAMPLE_REASON] = reason
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingMiddleware counts the calls that reach mw.
+func countingMiddleware(calls *int) Middleware[string, string] {
+	return func(next RepoOp[string, string]) RepoOp[string, string] {
+		return func(ctx context.Context, in string) (OutputWithMeta[string], error) {
+			*calls++
+			return next(ctx, in)
+		}
+	}
+}
+
+func echo(ctx context.Context, in string) (OutputWithMeta[string], error) {
+	return OutputWithMeta[string]{Data: in}, nil
+}
+
+func sampleRun(s Sampler, n int) []bool {
+	out := make([]bool, n)
+	for i := range out {
+		out[i] = s(context.Background())
+	}
+	return out
+}
+
+func TestProbabilistic(t *testing.T) {
+	assert.NotContains(t, sampleRun(Probabilistic(0), 100), true)
+	assert.NotContains(t, sampleRun(Probabilistic(1), 100), false)
+
+	first := sampleRun(ProbabilisticSeeded(0.3, 42), 1000)
+	assert.Equal(t, first, sampleRun(ProbabilisticSeeded(0.3, 42), 1000), "the same seed samples the same requests")
+	assert.NotEqual(t, first, sampleRun(ProbabilisticSeeded(0.3, 7), 1000))
+
+	sampled := 0
+	for _, s := range first {
+		if s {
+			sampled++
+		}
+	}
+	assert.InDelta(t, 300, sampled, 50)
+}
+
+func TestRatePerSecond(t *testing.T) {
+	// Every decision advances the clock by 250ms, so each run of four fills one second.
+	fakeClock(t, at(12, 0), 250*time.Millisecond)
+	s := RatePerSecond(3)
+
+	assert.Equal(t, []bool{true, true, true, false}, sampleRun(s, 4))
+	assert.Equal(t, []bool{true, true, true, false}, sampleRun(s, 4), "the budget refills in the next second")
+}
+
+func TestSampled(t *testing.T) {
+	failing := func(ctx context.Context, in string) (OutputWithMeta[string], error) {
+		return OutputWithMeta[string]{Data: in}, errors.New("boom")
+	}
+	cases := []struct {
+		name    string
+		policy  SamplingPolicy
+		op      RepoOp[string, string]
+		step    time.Duration
+		sampled bool
+		reason  string
+	}{
+		{"head", SamplingPolicy{Head: AlwaysSample()}, echo, 0, true, SAMPLE_REASON_HEAD},
+		{"zero policy", SamplingPolicy{}, failing, time.Second, false, ""},
+		{"not head sampled", SamplingPolicy{Head: Probabilistic(0), OnError: true, SlowerThan: time.Second}, echo, 0, false, ""},
+		{"error", SamplingPolicy{OnError: true}, failing, 0, true, SAMPLE_REASON_ERROR},
+		{"slow", SamplingPolicy{SlowerThan: 100 * time.Millisecond}, echo, 200 * time.Millisecond, true, SAMPLE_REASON_SLOW},
+		{"fast", SamplingPolicy{SlowerThan: 100 * time.Millisecond}, echo, 50 * time.Millisecond, false, ""},
+		{"head wins over error", SamplingPolicy{Head: AlwaysSample(), OnError: true}, failing, 0, true, SAMPLE_REASON_HEAD},
+		{"error wins over slow", SamplingPolicy{OnError: true, SlowerThan: 100 * time.Millisecond}, failing, time.Second, true, SAMPLE_REASON_ERROR},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClock(t, at(12, 0), tc.step)
+			calls, ran := 0, 0
+			op := func(ctx context.Context, in string) (OutputWithMeta[string], error) {
+				ran++
+				return tc.op(ctx, in)
+			}
+
+			out, _ := Sampled(tc.policy, countingMiddleware(&calls))(op)(context.Background(), "in")
+			assert.Equal(t, 1, ran, "the operation runs once, sampled or not")
+			assert.Equal(t, "in", out.Data)
+			assert.Equal(t, tc.sampled, out.Meta[SAMPLED])
+			if tc.sampled {
+				assert.Equal(t, 1, calls)
+				assert.Equal(t, tc.reason, out.Meta[SAMPLE_REASON])
+			} else {
+				assert.Zero(t, calls)
+				assert.NotContains(t, out.Meta, SAMPLE_REASON)
+			}
+		})
+	}
+}
+
+func TestSampledDecisionReachesNestedOps(t *testing.T) {
+	heads := 0
+	head := func(ctx context.Context) bool {
+		heads++
+		return heads == 1
+	}
+	policy := SamplingPolicy{Head: head}
+
+	var outer, inner int
+	var decision, decided bool
+	nested := Sampled(policy, countingMiddleware(&inner))(func(ctx context.Context, in string) (OutputWithMeta[string], error) {
+		decision, decided = SamplingDecision(ctx)
+		return echo(ctx, in)
+	})
+	op := Sampled(policy, countingMiddleware(&outer))(nested)
+
+	out, err := op(context.Background(), "in")
+	require.NoError(t, err)
+	assert.Equal(t, 1, heads, "the head decision is taken once per request")
+	assert.Equal(t, 1, outer)
+	assert.Equal(t, 1, inner, "the nested op reuses the outer decision")
+	assert.True(t, decided)
+	assert.True(t, decision)
+	assert.Equal(t, true, out.Meta[SAMPLED])
+
+	_, err = op(WithSamplingDecision(context.Background(), false), "in")
+	require.NoError(t, err)
+	assert.Equal(t, 1, heads, "a decision already on the context is kept")
+	assert.Equal(t, 1, outer)
+	assert.Equal(t, 1, inner)
+}
@@ -0,0 +1,188 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	SHADOWED = "shadowed"
+)
+
+// Comparator reports whether the primary and secondary outputs agree, and if not, how.
+type Comparator[Out any] func(primary, secondary Out) (equal bool, diff string)
+
+// ShadowMismatch is reported to the sink when the secondary disagrees with the primary.
+type ShadowMismatch[In any, Out any] struct {
+	Input        In
+	Primary      Out
+	PrimaryErr   error
+	Secondary    Out
+	SecondaryErr error
+	Diff         string
+}
+
+type ShadowConfig[In any, Out any] struct {
+	// Compare defaults to reflect.DeepEqual.
+	Compare Comparator[Out]
+	// Sink receives mismatches from a background goroutine.
+	Sink func(ctx context.Context, m ShadowMismatch[In, Out])
+	// Clone copies the primary output before it is handed back to the caller, so
+	// outer middlewares (e.g. MaskOutput) mutating it in place don't skew the comparison.
+	Clone func(out Out) Out
+	// Timeout bounds the secondary call. Defaults to 5s.
+	Timeout time.Duration
+	// MaxInFlight caps concurrent shadow calls; calls over the cap are not shadowed.
+	// Defaults to 16.
+	MaxInFlight int
+}
+
+// Shadow calls secondary alongside the primary chain and compares the results in the
+// background. The primary result is returned as soon as it is available; the secondary
+// can neither fail nor slow down the caller. SHADOWED in Meta tells whether the call
+// was shadowed or dropped because MaxInFlight was reached.
+func Shadow[In any, Out any](secondary RepoOp[In, Out], cfg ShadowConfig[In, Out]) Middleware[In, Out] {
+	if cfg.Compare == nil {
+		cfg.Compare = func(p, s Out) (bool, string) {
+			if reflect.DeepEqual(p, s) {
+				return true, ""
+			}
+			return false, fmt.Sprintf("primary %+v != secondary %+v", p, s)
+		}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.MaxInFlight <= 0 {
+		cfg.MaxInFlight = 16
+	}
+	slots := make(chan struct{}, cfg.MaxInFlight)
+
+	return func(next RepoOp[In, Out]) RepoOp[In, Out] {
+		return func(ctx context.Context, input In) (OutputWithMeta[Out], error) {
+			if IsShadowDisabled(ctx) {
+				return next(ctx, input)
+			}
+			select {
+			case slots <- struct{}{}:
+			default:
+				out, err := next(ctx, input)
+				return markShadowed(out, false), err
+			}
+
+			primary := make(chan ShadowMismatch[In, Out], 1)
+			go func() {
+				defer func() { <-slots }()
+				shadowCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), cfg.Timeout)
+				defer cancel()
+
+				m := ShadowMismatch[In, Out]{Input: input}
+				m.Secondary, m.SecondaryErr = callSecondary(shadowCtx, secondary, input)
+				p := <-primary
+				m.Primary, m.PrimaryErr = p.Primary, p.PrimaryErr
+
+				switch {
+				case (m.PrimaryErr == nil) != (m.SecondaryErr == nil):
+					m.Diff = fmt.Sprintf("primary error %v, secondary error %v", m.PrimaryErr, m.SecondaryErr)
+				case m.PrimaryErr == nil:
+					equal, diff := cfg.Compare(m.Primary, m.Secondary)
+					if equal {
+						return
+					}
+					m.Diff = diff
+				default:
+					return
+				}
+				if cfg.Sink != nil {
+					cfg.Sink(shadowCtx, m)
+				}
+			}()
+
+			defer func() {
+				if r := recover(); r != nil {
+					primary <- ShadowMismatch[In, Out]{PrimaryErr: fmt.Errorf("primary panic: %v", r)}
+					panic(r)
+				}
+			}()
+			out, err := next(ctx, input)
+			snapshot := out.Data
+			if cfg.Clone != nil && err == nil {
+				snapshot = cfg.Clone(out.Data)
+			}
+			primary <- ShadowMismatch[In, Out]{Primary: snapshot, PrimaryErr: err}
+			return markShadowed(out, true), err
+		}
+	}
+}
+
+// callSecondary runs the secondary, turning a panic into an error.
+func callSecondary[In any, Out any](ctx context.Context, secondary RepoOp[In, Out], input In) (data Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("shadow panic: %v", r)
+		}
+	}()
+	out, err := secondary(ctx, input)
+	return out.Data, err
+}
+
+func markShadowed[Out any](out OutputWithMeta[Out], shadowed bool) OutputWithMeta[Out] {
+	if out.Meta == nil {
+		out.Meta = make(map[string]interface{})
+	}
+	out.Meta[SHADOWED] = shadowed
+	return out
+}
+
+// UnorderedSlices compares slices as sets keyed by key, ignoring order. equal compares
+// elements sharing a key; nil uses reflect.DeepEqual.
+func UnorderedSlices[T any](key func(T) string, equal func(a, b T) bool) Comparator[[]T] {
+	if equal == nil {
+		equal = func(a, b T) bool { return reflect.DeepEqual(a, b) }
+	}
+	return func(primary, secondary []T) (bool, string) {
+		byKey := make(map[string]T, len(secondary))
+		for _, s := range secondary {
+			byKey[key(s)] = s
+		}
+
+		var missing, changed, extra []string
+		for _, p := range primary {
+			k := key(p)
+			s, ok := byKey[k]
+			switch {
+			case !ok:
+				missing = append(missing, k)
+			case !equal(p, s):
+				changed = append(changed, k)
+			}
+			delete(byKey, k)
+		}
+		for k := range byKey {
+			extra = append(extra, k)
+		}
+		if len(missing)+len(changed)+len(extra) == 0 && len(primary) == len(secondary) {
+			return true, ""
+		}
+		sort.Strings(extra)
+
+		var diff []string
+		if len(missing) > 0 {
+			diff = append(diff, "missing in secondary: "+strings.Join(missing, ","))
+		}
+		if len(extra) > 0 {
+			diff = append(diff, "only in secondary: "+strings.Join(extra, ","))
+		}
+		if len(changed) > 0 {
+			diff = append(diff, "changed: "+strings.Join(changed, ","))
+		}
+		if len(diff) == 0 {
+			diff = append(diff, fmt.Sprintf("duplicate keys: %d primary, %d secondary", len(primary), len(secondary)))
+		}
+		return false, strings.Join(diff, "; ")
+	}
+}
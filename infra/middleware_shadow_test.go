@@ -0,0 +1,60 @@
+package infra
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShadowReportsMismatchesWithoutDelayingPrimary(t *testing.T) {
+	primary := func(ctx context.Context, in string) (OutputWithMeta[[]string], error) {
+		return OutputWithMeta[[]string]{Data: []string{"a", "b", "c"}}, nil
+	}
+	// The secondary is held until both primary calls have returned.
+	release := make(chan struct{})
+	secondary := func(ctx context.Context, in string) (OutputWithMeta[[]string], error) {
+		<-release
+		if in == "same" {
+			return OutputWithMeta[[]string]{Data: []string{"c", "a", "b"}}, nil
+		}
+		return OutputWithMeta[[]string]{Data: []string{"a", "d"}}, nil
+	}
+
+	mismatches := make(chan ShadowMismatch[string, []string], 2)
+	op := Shadow(secondary, ShadowConfig[string, []string]{
+		Compare: UnorderedSlices(func(s string) string { return s }, nil),
+		Sink: func(ctx context.Context, m ShadowMismatch[string, []string]) {
+			mismatches <- m
+		},
+	})(primary)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		out, err := op(context.Background(), "same")
+		assert.NoError(t, err)
+		assert.Equal(t, true, out.Meta[SHADOWED])
+		_, _ = op(context.Background(), "different")
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the primary waited for the secondary")
+	}
+	close(release)
+
+	select {
+	case m := <-mismatches:
+		assert.Equal(t, "different", m.Input)
+		assert.Equal(t, "missing in secondary: b,c; only in secondary: d", m.Diff)
+	case <-time.After(time.Second):
+		t.Fatal("no mismatch reported")
+	}
+	select {
+	case m := <-mismatches:
+		t.Fatalf("unexpected mismatch %+v", m)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
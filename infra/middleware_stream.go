@@ -0,0 +1,185 @@
+package infra
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+)
+
+var (
+	ITEM_COUNT = "item_count"
+)
+
+// StreamWithMeta carries a lazily evaluated sequence of items. Stream middlewares do
+// their work as the sequence is consumed, so Meta is only complete once Items has
+// been fully ranged over (or the consumer stopped early).
+type StreamWithMeta[T any] struct {
+	Items iter.Seq2[T, error]
+	Meta  map[string]interface{}
+}
+
+type StreamOp[In any, Out any] func(ctx context.Context, input In) StreamWithMeta[Out]
+type StreamMiddleware[In any, Out any] func(StreamOp[In, Out]) StreamOp[In, Out]
+
+// ChainStream composes stream middlewares around a base operation.
+func ChainStream[In any, Out any](base StreamOp[In, Out], mws ...StreamMiddleware[In, Out]) StreamOp[In, Out] {
+	for i := len(mws) - 1; i >= 0; i-- {
+		base = mws[i](base)
+	}
+	return base
+}
+
+// GateStream is Gate for stream middlewares.
+func GateStream[In any, Out any](mw StreamMiddleware[In, Out], disabledFn func(ctx context.Context) bool) StreamMiddleware[In, Out] {
+	return func(next StreamOp[In, Out]) StreamOp[In, Out] {
+		wrapped := mw(next)
+		return func(ctx context.Context, in In) StreamWithMeta[Out] {
+			if disabledFn != nil && disabledFn(ctx) {
+				return next(ctx, in)
+			}
+			return wrapped(ctx, in)
+		}
+	}
+}
+
+// StreamEntry is a single named stream middleware, described like a MiddlewareEntry.
+type StreamEntry[In any, Out any] struct {
+	Name       string
+	Condition  string
+	GateKind   string
+	Middleware StreamMiddleware[In, Out]
+}
+
+// GatedStream returns an entry for mw wrapped in GateStream, recording the name of
+// disabledFn as its condition.
+func GatedStream[In any, Out any](name string, mw StreamMiddleware[In, Out], disabledFn func(ctx context.Context) bool) StreamEntry[In, Out] {
+	return StreamEntry[In, Out]{
+		Name:       name,
+		Condition:  funcName(disabledFn),
+		GateKind:   GATE_UNLESS,
+		Middleware: GateStream(mw, disabledFn),
+	}
+}
+
+// ChainStreamEntries composes the entries around base, the first entry outermost, and
+// describes the resulting chain.
+func ChainStreamEntries[In any, Out any](base StreamOp[In, Out], entries ...StreamEntry[In, Out]) (StreamOp[In, Out], ChainDescription) {
+	var d ChainDescription
+	names := make([]string, len(entries))
+	mws := make([]StreamMiddleware[In, Out], len(entries))
+	for i, e := range entries {
+		names[i] = e.Name
+		mws[i] = e.Middleware
+		d.Entries = append(d.Entries, EntryDescription{Name: e.Name, Condition: e.Condition, GateKind: e.GateKind})
+	}
+	d.Issues = orderingIssues(names, DefaultOrderingRules)
+	return ChainStream(base, mws...), d
+}
+
+// StreamTimer measures the time from the first pull until the sequence ends.
+func StreamTimer[In any, Out any]() StreamMiddleware[In, Out] {
+	return func(next StreamOp[In, Out]) StreamOp[In, Out] {
+		return func(ctx context.Context, input In) StreamWithMeta[Out] {
+			out := next(ctx, input)
+			if IsTimingDisabled(ctx) {
+				return out
+			}
+			out = withStreamMeta(out)
+			items, meta := out.Items, out.Meta
+			out.Items = func(yield func(Out, error) bool) {
+				start := time.Now()
+				defer func() { meta[DURATION] = time.Since(start) }()
+				for item, err := range items {
+					if !yield(item, err) {
+						return
+					}
+				}
+			}
+			return out
+		}
+	}
+}
+
+// StreamMask applies maskFunc to every item as it passes through.
+func StreamMask[In any, Out any](maskFunc func(item Out) Out) StreamMiddleware[In, Out] {
+	return func(next StreamOp[In, Out]) StreamOp[In, Out] {
+		return func(ctx context.Context, input In) StreamWithMeta[Out] {
+			out := next(ctx, input)
+			if IsMaskingDisabled(ctx) || maskFunc == nil {
+				return out
+			}
+			out = withStreamMeta(out)
+			items := out.Items
+			out.Meta[MASKED] = true
+			out.Items = func(yield func(Out, error) bool) {
+				for item, err := range items {
+					if err == nil {
+						item = maskFunc(item)
+					}
+					if !yield(item, err) {
+						return
+					}
+				}
+			}
+			return out
+		}
+	}
+}
+
+// StreamLogging logs the start of the stream and, when it ends, the number of items
+// yielded and any error. The count is also recorded under ITEM_COUNT.
+func StreamLogging[In any, Out any](logger func(ctx context.Context, msg string)) StreamMiddleware[In, Out] {
+	return func(next StreamOp[In, Out]) StreamOp[In, Out] {
+		return func(ctx context.Context, input In) StreamWithMeta[Out] {
+			out := next(ctx, input)
+			if IsLoggingDisabled(ctx) {
+				return out
+			}
+			out = withStreamMeta(out)
+			items, meta := out.Items, out.Meta
+			out.Items = func(yield func(Out, error) bool) {
+				logger(ctx, fmt.Sprintf("🟢 [START] Stream\n  ↳ Input: %+v", input))
+				count := 0
+				var streamErr error
+				defer func() {
+					meta[ITEM_COUNT] = count
+					if streamErr != nil {
+						logger(ctx, fmt.Sprintf("[END] Stream FAILED after %d item(s)\n  ↳ Error: %v", count, streamErr))
+					} else {
+						logger(ctx, fmt.Sprintf("[END] Stream SUCCESS\n  ↳ Items: %d", count))
+					}
+				}()
+				for item, err := range items {
+					if err != nil {
+						streamErr = err
+					} else {
+						count++
+					}
+					if !yield(item, err) {
+						return
+					}
+				}
+			}
+			return out
+		}
+	}
+}
+
+// StreamError returns a stream that yields err once.
+func StreamError[Out any](err error) StreamWithMeta[Out] {
+	return StreamWithMeta[Out]{Items: func(yield func(Out, error) bool) {
+		var zero Out
+		yield(zero, err)
+	}}
+}
+
+func withStreamMeta[T any](out StreamWithMeta[T]) StreamWithMeta[T] {
+	if out.Meta == nil {
+		out.Meta = make(map[string]interface{})
+	}
+	if out.Items == nil {
+		out.Items = func(yield func(T, error) bool) {}
+	}
+	return out
+}
@@ -0,0 +1,144 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamOf returns a stream op yielding items, then err when it is not nil. pulled
+// counts the items the consumer asked for.
+func streamOf(pulled *int, err error, items ...string) StreamOp[string, string] {
+	return func(ctx context.Context, in string) StreamWithMeta[string] {
+		return StreamWithMeta[string]{Items: func(yield func(string, error) bool) {
+			for _, item := range items {
+				*pulled++
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if err != nil {
+				yield("", err)
+			}
+		}}
+	}
+}
+
+// streamTag returns a stream middleware appending name to every item.
+func streamTag(name string) StreamMiddleware[string, string] {
+	return StreamMask[string](func(item string) string { return item + name })
+}
+
+func collect(out StreamWithMeta[string]) ([]string, error) {
+	var items []string
+	for item, err := range out.Items {
+		if err != nil {
+			return items, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func TestChainStreamOrder(t *testing.T) {
+	pulled := 0
+	op := ChainStream(streamOf(&pulled, nil, "x", "y"), streamTag("a"), streamTag("b"))
+
+	items, err := collect(op(context.Background(), ""))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xba", "yba"}, items, "the first middleware is outermost")
+}
+
+func TestStreamMiddlewaresMeta(t *testing.T) {
+	var logs []string
+	logger := func(ctx context.Context, msg string) { logs = append(logs, msg) }
+	pulled := 0
+	op := ChainStream(streamOf(&pulled, nil, "a", "b", "c"),
+		StreamLogging[string, string](logger),
+		StreamTimer[string, string](),
+		StreamMask[string](func(item string) string { return "*" }),
+	)
+
+	out := op(context.Background(), "in")
+	assert.Equal(t, true, out.Meta[MASKED])
+	assert.NotContains(t, out.Meta, ITEM_COUNT, "meta is filled as the stream is consumed")
+	assert.NotContains(t, out.Meta, DURATION)
+	assert.Zero(t, pulled, "nothing is pulled before the consumer ranges")
+
+	items, err := collect(out)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*", "*", "*"}, items)
+	assert.Equal(t, 3, out.Meta[ITEM_COUNT])
+	assert.Contains(t, out.Meta, DURATION)
+	require.Len(t, logs, 2)
+	assert.Contains(t, logs[1], "Items: 3")
+}
+
+func TestStreamEarlyStop(t *testing.T) {
+	pulled := 0
+	op := ChainStream(streamOf(&pulled, nil, "a", "b", "c"),
+		StreamLogging[string, string](func(ctx context.Context, msg string) {}),
+		StreamTimer[string, string](),
+	)
+
+	out := op(context.Background(), "in")
+	for range out.Items {
+		break
+	}
+	assert.Equal(t, 1, pulled, "stopping the consumer stops the source")
+	assert.Equal(t, 1, out.Meta[ITEM_COUNT])
+	assert.Contains(t, out.Meta, DURATION)
+}
+
+func TestStreamMidStreamError(t *testing.T) {
+	boom := errors.New("boom")
+	var logs []string
+	pulled := 0
+	masked := 0
+	op := ChainStream(streamOf(&pulled, boom, "a", "b"),
+		StreamLogging[string, string](func(ctx context.Context, msg string) { logs = append(logs, msg) }),
+		StreamMask[string](func(item string) string { masked++; return item }),
+	)
+
+	out := op(context.Background(), "in")
+	items, err := collect(out)
+	assert.ErrorIs(t, err, boom, "the error reaches the consumer")
+	assert.Equal(t, []string{"a", "b"}, items)
+	assert.Equal(t, 2, masked, "the error item is not masked")
+	assert.Equal(t, 2, out.Meta[ITEM_COUNT])
+	require.Len(t, logs, 2)
+	assert.Contains(t, logs[1], "FAILED after 2 item(s)")
+}
+
+func TestStreamError(t *testing.T) {
+	boom := errors.New("boom")
+	op := ChainStream(func(ctx context.Context, in string) StreamWithMeta[string] { return StreamError[string](boom) },
+		StreamLogging[string, string](func(ctx context.Context, msg string) {}),
+	)
+
+	out := op(context.Background(), "in")
+	items, err := collect(out)
+	assert.ErrorIs(t, err, boom)
+	assert.Empty(t, items)
+	assert.Equal(t, 0, out.Meta[ITEM_COUNT])
+}
+
+func TestGatedStream(t *testing.T) {
+	pulled := 0
+	entry := GatedStream("Tag", streamTag("a"), IsMaskingDisabled)
+	op, d := ChainStreamEntries(streamOf(&pulled, nil, "x"), entry)
+	assert.Equal(t, "Tag [unless IsMaskingDisabled] -> base", d.String())
+
+	items, err := collect(op(context.Background(), ""))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xa"}, items)
+
+	out := op(DisableMasking(context.Background()), "")
+	items, err = collect(out)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"x"}, items)
+	assert.NotContains(t, out.Meta, MASKED)
+}
@@ -0,0 +1,83 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallback(t *testing.T) {
+	errDown := errors.New("primary down")
+	errBadInput := errors.New("bad input")
+	errSnapshot := errors.New("snapshot missing")
+
+	primary := func(ctx context.Context, in string) (OutputWithMeta[[]string], error) {
+		if in == "bad" {
+			return OutputWithMeta[[]string]{}, errBadInput
+		}
+		return OutputWithMeta[[]string]{}, errDown
+	}
+	var fallbackCalls int
+	fallback := func(ctx context.Context, in string) (OutputWithMeta[[]string], error) {
+		fallbackCalls++
+		if in == "missing" {
+			return OutputWithMeta[[]string]{}, errSnapshot
+		}
+		return OutputWithMeta[[]string]{Data: []string{"stale " + in}}, nil
+	}
+	op := Fallback(func(err error) bool { return errors.Is(err, errDown) }, fallback)(primary)
+	ctx := context.Background()
+
+	out, err := op(ctx, "soup")
+	require.NoError(t, err, "classified error is answered by the fallback")
+	assert.Equal(t, []string{"stale soup"}, out.Data)
+	assert.Equal(t, true, out.Meta[DEGRADED])
+	assert.Equal(t, errDown, out.Meta[ORIGINAL_ERROR])
+
+	calls := fallbackCalls
+	_, err = op(ctx, "bad")
+	assert.Equal(t, errBadInput, err, "unclassified error is returned as is")
+	assert.Equal(t, calls, fallbackCalls, "fallback is not called for unclassified errors")
+
+	_, err = op(ctx, "missing")
+	assert.ErrorIs(t, err, errDown, "both errors are returned when the fallback fails")
+	assert.ErrorIs(t, err, errSnapshot)
+
+	_, err = op(DisableFallback(ctx), "soup")
+	assert.Equal(t, errDown, err)
+}
+
+func TestRetryIf(t *testing.T) {
+	errTransient := errors.New("connection reset")
+	errPermanent := errors.New("invalid input")
+
+	var calls int
+	failWith := func(err error) RepoOp[string, int] {
+		return func(ctx context.Context, in string) (OutputWithMeta[int], error) {
+			calls++
+			return OutputWithMeta[int]{}, err
+		}
+	}
+	retryable := func(err error) bool { return !errors.Is(err, errPermanent) }
+	ctx := context.Background()
+
+	calls = 0
+	out, err := RetryIf[string, int](2, 0, retryable)(failWith(errTransient))(ctx, "x")
+	assert.Equal(t, errTransient, err)
+	assert.Equal(t, 3, calls, "retryable errors are retried up to maxRetries")
+	assert.Equal(t, 2, out.Meta[RETRY_COUNT])
+
+	calls = 0
+	out, err = RetryIf[string, int](2, 0, retryable)(failWith(errPermanent))(ctx, "x")
+	assert.Equal(t, errPermanent, err)
+	assert.Equal(t, 1, calls, "other errors are returned at once")
+	assert.Equal(t, 0, out.Meta[RETRY_COUNT])
+
+	calls = 0
+	_, err = Retry[string, int](2, 0)(failWith(errPermanent))(ctx, "x")
+	assert.Equal(t, errPermanent, err)
+	assert.Equal(t, 3, calls, "Retry retries every error")
+}
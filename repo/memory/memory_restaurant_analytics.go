@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	restaurant "github.com/testingrepo/domain"
+)
+
+func (r *RestaurantRepo) AverageRating(ctx context.Context, id string) (*restaurant.RatingSummary, error) {
+	var s restaurant.RatingSummary
+	err := r.view(ctx, id, func(rest *restaurant.Restaurant) { s = rest.RatingSummary() })
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *RestaurantRepo) TopRatedByCity(ctx context.Context, city string, limit int) ([]*restaurant.RatedRestaurant, error) {
+	if city == "" {
+		return nil, restaurant.ErrEmptyCriteria
+	}
+	address := restaurant.Address{City: city}
+	found, err := r.find(ctx, func(rest *restaurant.Restaurant) bool {
+		return len(rest.Ratings) > 0 && address.Matches(rest.Address)
+	})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*restaurant.RatedRestaurant, len(found))
+	for i, rest := range found {
+		s := rest.RatingSummary()
+		results[i] = &restaurant.RatedRestaurant{Restaurant: rest, Average: s.Average, Count: s.Count}
+	}
+	slices.SortFunc(results, restaurant.CompareRated)
+	return results[:min(len(results), restaurant.NormalizeLimit(limit))], nil
+}
+
+func (r *RestaurantRepo) MenuPriceStats(ctx context.Context, id string) (*restaurant.PriceStats, error) {
+	var s restaurant.PriceStats
+	err := r.view(ctx, id, func(rest *restaurant.Restaurant) { s = rest.MenuPriceStats() })
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// view calls fn with the stored restaurant id under the read lock. fn must not keep it.
+func (r *RestaurantRepo) view(ctx context.Context, id string, fn func(rest *restaurant.Restaurant)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rest, ok := r.byID[id]
+	if !ok {
+		return fmt.Errorf("restaurant %s: %w", id, restaurant.ErrNotFound)
+	}
+	fn(rest)
+	return nil
+}
@@ -0,0 +1,364 @@
+// Package memory provides an in-process, concurrency-safe RestaurantRepository for
+// tests and local development. It follows the semantics of mongo.RestaurantRepo.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"slices"
+	"sync"
+
+	restaurant "github.com/testingrepo/domain"
+)
+
+var (
+	_ restaurant.RestaurantRepository      = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantPagedReader     = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantStreamer        = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantQuerier         = (*RestaurantRepo)(nil)
+	_ restaurant.UnitOfWork                = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantGeoReader       = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantTextSearcher    = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantAnalyticsReader = (*RestaurantRepo)(nil)
+)
+
+// RestaurantRepo stores restaurants in memory. Restaurants are copied on the way in
+// and out, so callers (and masking middlewares) never mutate the stored state.
+type RestaurantRepo struct {
+	mu    sync.RWMutex
+	byID  map[string]*restaurant.Restaurant
+	order []string
+}
+
+func NewRestaurantRepo() *RestaurantRepo {
+	return &RestaurantRepo{byID: make(map[string]*restaurant.Restaurant)}
+}
+
+// LoadFixtures inserts the restaurants of a JSON array in the RestaurantJSON format.
+func (r *RestaurantRepo) LoadFixtures(ctx context.Context, src io.Reader) error {
+	var docs []restaurant.RestaurantJSON
+	if err := json.NewDecoder(src).Decode(&docs); err != nil {
+		return fmt.Errorf("decode fixtures: %w", err)
+	}
+	for i := range docs {
+		if err := r.InsertRestaurant(ctx, docs[i].RestaurantFromJSONToDTO()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFixturesFile inserts the restaurants of a JSON fixture file.
+func (r *RestaurantRepo) LoadFixturesFile(ctx context.Context, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return r.LoadFixtures(ctx, f)
+}
+
+// Snapshot returns a copy of every stored restaurant in insertion order.
+func (r *RestaurantRepo) Snapshot() []*restaurant.Restaurant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]*restaurant.Restaurant, len(r.order))
+	for i, id := range r.order {
+		out[i] = r.byID[id].Clone()
+	}
+	return out
+}
+
+// Restore replaces the stored restaurants with a snapshot.
+func (r *RestaurantRepo) Restore(snapshot []*restaurant.Restaurant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byID = make(map[string]*restaurant.Restaurant, len(snapshot))
+	r.order = r.order[:0]
+	for _, rest := range snapshot {
+		r.byID[rest.ID] = rest.Clone()
+		r.order = append(r.order, rest.ID)
+	}
+}
+
+// Reset removes every restaurant.
+func (r *RestaurantRepo) Reset() {
+	r.Restore(nil)
+}
+
+func (r *RestaurantRepo) FindByName(ctx context.Context, name string) ([]*restaurant.Restaurant, error) {
+	return r.find(ctx, func(rest *restaurant.Restaurant) bool {
+		return rest.Name == name
+	})
+}
+
+func (r *RestaurantRepo) FindByAddress(ctx context.Context, address restaurant.Address) ([]*restaurant.Restaurant, error) {
+	if address.IsZero() {
+		return nil, restaurant.ErrEmptyCriteria
+	}
+	return r.find(ctx, func(rest *restaurant.Restaurant) bool {
+		return address.Matches(rest.Address)
+	})
+}
+
+func (r *RestaurantRepo) FindByOwner(ctx context.Context, owner string) ([]*restaurant.Restaurant, error) {
+	return r.find(ctx, ownerMatcher(owner))
+}
+
+func (r *RestaurantRepo) FindByRating(ctx context.Context, score int) ([]*restaurant.Restaurant, error) {
+	return r.find(ctx, ratingMatcher(score))
+}
+
+func (r *RestaurantRepo) FindByMenuItem(ctx context.Context, item string) ([]*restaurant.Restaurant, error) {
+	return r.find(ctx, menuItemMatcher(item))
+}
+
+func (r *RestaurantRepo) Find(ctx context.Context, q restaurant.RestaurantQuery) ([]*restaurant.Restaurant, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return r.find(ctx, q.Matches)
+}
+
+// FindNear measures distances with domain.HaversineMeters, which uses the Earth radius
+// of Mongo's 2dsphere index, so results agree with mongo.RestaurantRepo up to rounding.
+func (r *RestaurantRepo) FindNear(ctx context.Context, point restaurant.GeoPoint, radiusMeters float64) ([]*restaurant.RestaurantDistance, error) {
+	if err := restaurant.ValidateNear(point, radiusMeters); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	results := make([]*restaurant.RestaurantDistance, 0)
+	for _, id := range r.order {
+		rest := r.byID[id]
+		if rest.Address.Location == nil {
+			continue
+		}
+		if d := restaurant.HaversineMeters(point, *rest.Address.Location); d <= radiusMeters {
+			results = append(results, &restaurant.RestaurantDistance{Restaurant: rest.Clone(), DistanceMeters: d})
+		}
+	}
+	r.mu.RUnlock()
+	slices.SortStableFunc(results, func(a, b *restaurant.RestaurantDistance) int {
+		return cmp.Compare(a.DistanceMeters, b.DistanceMeters)
+	})
+	return results, nil
+}
+
+func (r *RestaurantRepo) FindByNamePaged(ctx context.Context, name string, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, func(rest *restaurant.Restaurant) bool { return rest.Name == name }, page)
+}
+
+func (r *RestaurantRepo) FindByOwnerPaged(ctx context.Context, owner string, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, ownerMatcher(owner), page)
+}
+
+func (r *RestaurantRepo) FindByRatingPaged(ctx context.Context, score int, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, ratingMatcher(score), page)
+}
+
+func (r *RestaurantRepo) FindByMenuItemPaged(ctx context.Context, item string, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, menuItemMatcher(item), page)
+}
+
+func (r *RestaurantRepo) StreamAll(ctx context.Context) iter.Seq2[*restaurant.Restaurant, error] {
+	return r.stream(ctx, func(*restaurant.Restaurant) bool { return true })
+}
+
+func (r *RestaurantRepo) StreamByName(ctx context.Context, name string) iter.Seq2[*restaurant.Restaurant, error] {
+	return r.stream(ctx, func(rest *restaurant.Restaurant) bool { return rest.Name == name })
+}
+
+func (r *RestaurantRepo) InsertRestaurant(ctx context.Context, rest *restaurant.Restaurant) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if rest.ID == "" {
+		rest.ID = newID()
+	}
+	if _, exists := r.byID[rest.ID]; exists {
+		return fmt.Errorf("restaurant %s: %w", rest.ID, restaurant.ErrAlreadyExists)
+	}
+	r.byID[rest.ID] = rest.Clone()
+	r.order = append(r.order, rest.ID)
+	return nil
+}
+
+func (r *RestaurantRepo) UpdateMenu(ctx context.Context, id string, menu []restaurant.MenuItem) error {
+	return r.update(ctx, id, func(rest *restaurant.Restaurant) {
+		rest.Menu = append([]restaurant.MenuItem{}, menu...)
+	})
+}
+
+func (r *RestaurantRepo) AddRating(ctx context.Context, id string, rating restaurant.Rating) error {
+	return r.update(ctx, id, func(rest *restaurant.Restaurant) {
+		rest.Ratings = append(rest.Ratings, rating)
+	})
+}
+
+// UpdateEmployee replaces the employee with the same name, or appends emp if there is none.
+func (r *RestaurantRepo) UpdateEmployee(ctx context.Context, id string, emp restaurant.Employee) error {
+	return r.update(ctx, id, func(rest *restaurant.Restaurant) {
+		for i, e := range rest.Employees {
+			if e.Name == emp.Name {
+				rest.Employees[i] = emp
+				return
+			}
+		}
+		rest.Employees = append(rest.Employees, emp)
+	})
+}
+
+func (r *RestaurantRepo) update(ctx context.Context, id string, fn func(rest *restaurant.Restaurant)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rest, ok := r.byID[id]
+	if !ok {
+		return fmt.Errorf("restaurant %s: %w", id, restaurant.ErrNotFound)
+	}
+	fn(rest)
+	return nil
+}
+
+func (r *RestaurantRepo) find(ctx context.Context, match func(*restaurant.Restaurant) bool) ([]*restaurant.Restaurant, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	restaurants := make([]*restaurant.Restaurant, 0)
+	for _, id := range r.order {
+		if rest := r.byID[id]; match(rest) {
+			restaurants = append(restaurants, rest.Clone())
+		}
+	}
+	return restaurants, nil
+}
+
+// stream yields copies of the matching restaurants without holding the lock while
+// the consumer handles an item.
+func (r *RestaurantRepo) stream(ctx context.Context, match func(*restaurant.Restaurant) bool) iter.Seq2[*restaurant.Restaurant, error] {
+	return func(yield func(*restaurant.Restaurant, error) bool) {
+		r.mu.RLock()
+		ids := slices.Clone(r.order)
+		r.mu.RUnlock()
+
+		for _, id := range ids {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			r.mu.RLock()
+			rest, ok := r.byID[id]
+			if ok && match(rest) {
+				rest = rest.Clone()
+			} else {
+				rest = nil
+			}
+			r.mu.RUnlock()
+			if rest != nil && !yield(rest, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *RestaurantRepo) findPage(ctx context.Context, match func(*restaurant.Restaurant) bool, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	page = page.Normalize()
+	key, ok := sortKeys[page.SortBy]
+	if !ok {
+		return nil, restaurant.ErrInvalidCursor
+	}
+	matches, err := r.find(ctx, match)
+	if err != nil {
+		return nil, err
+	}
+
+	compare := func(a, b *restaurant.Restaurant) int {
+		return cmp.Or(key(a, b), cmp.Compare(a.ID, b.ID))
+	}
+	if page.Descending {
+		asc := compare
+		compare = func(a, b *restaurant.Restaurant) int { return -asc(a, b) }
+	}
+	slices.SortFunc(matches, compare)
+
+	result := &restaurant.RestaurantPage{TotalCount: int64(len(matches))}
+	if page.Cursor != "" {
+		cursor, err := restaurant.DecodeCursor(page)
+		if err != nil {
+			return nil, err
+		}
+		last := &restaurant.Restaurant{ID: cursor.ID, Name: cursor.Name, Age: cursor.Age}
+		start, _ := slices.BinarySearchFunc(matches, last, compare)
+		for start < len(matches) && compare(matches[start], last) <= 0 {
+			start++
+		}
+		matches = matches[start:]
+	}
+
+	if len(matches) > page.Limit {
+		matches = matches[:page.Limit]
+		result.HasMore = true
+	}
+	result.Items = matches
+	if result.HasMore {
+		result.NextCursor = restaurant.EncodeCursor(page, matches[len(matches)-1])
+	}
+	return result, nil
+}
+
+// sortKeys compares restaurants by the page sort field; ties are broken by ID.
+var sortKeys = map[restaurant.SortField]func(a, b *restaurant.Restaurant) int{
+	restaurant.SortByID:   func(a, b *restaurant.Restaurant) int { return 0 },
+	restaurant.SortByName: func(a, b *restaurant.Restaurant) int { return cmp.Compare(a.Name, b.Name) },
+	restaurant.SortByAge:  func(a, b *restaurant.Restaurant) int { return cmp.Compare(a.Age, b.Age) },
+}
+
+func ownerMatcher(owner string) func(*restaurant.Restaurant) bool {
+	return func(rest *restaurant.Restaurant) bool {
+		return slices.Contains(rest.Owners, owner)
+	}
+}
+
+func ratingMatcher(score int) func(*restaurant.Restaurant) bool {
+	return func(rest *restaurant.Restaurant) bool {
+		return slices.ContainsFunc(rest.Ratings, func(rt restaurant.Rating) bool { return rt.Score == score })
+	}
+}
+
+func menuItemMatcher(item string) func(*restaurant.Restaurant) bool {
+	return func(rest *restaurant.Restaurant) bool {
+		return slices.ContainsFunc(rest.Menu, func(m restaurant.MenuItem) bool { return m.Name == item })
+	}
+}
+
+// newID returns a 24 character hex ID, the same shape as a Mongo ObjectID.
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithTransaction runs fn directly. The in-memory repository has no transactions:
+// writes are applied as they happen and are not rolled back when fn fails.
+func (r *RestaurantRepo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return fn(ctx)
+}
@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	restaurant "github.com/testingrepo/domain"
+	"github.com/testingrepo/repo/repotest"
+)
+
+const fixtures = `[
+	{"id": "1", "name": "Noodle Bar", "age": 3, "owners": ["Ann"], "menu": [{"name": "Pho", "price": 14}]},
+	{"id": "2", "name": "Burger Hut", "age": 9, "ratings": [{"score": 4, "user": "bob"}]},
+	{"id": "3", "name": "Apple Cafe", "age": 5}
+]`
+
+func TestRestaurantRepoFixturesAndSnapshots(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRestaurantRepo()
+	require.NoError(t, repo.LoadFixtures(ctx, strings.NewReader(fixtures)))
+
+	found, err := repo.FindByMenuItem(ctx, "Pho")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, "Noodle Bar", found[0].Name)
+
+	// Returned restaurants are copies.
+	found[0].Owners[0] = "****"
+	found, _ = repo.FindByOwner(ctx, "Ann")
+	assert.Len(t, found, 1)
+
+	snapshot := repo.Snapshot()
+	require.NoError(t, repo.AddRating(ctx, "1", restaurant.Rating{Score: 5}))
+	repo.Reset()
+	assert.Empty(t, repo.Snapshot())
+
+	repo.Restore(snapshot)
+	found, _ = repo.FindByRating(ctx, 5)
+	assert.Empty(t, found)
+	assert.Len(t, repo.Snapshot(), 3)
+}
+
+func TestRestaurantRepoPaging(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRestaurantRepo()
+	require.NoError(t, repo.LoadFixtures(ctx, strings.NewReader(fixtures)))
+
+	all := restaurant.Or(restaurant.Gte(restaurant.FieldAge, 0))
+	_, err := repo.Find(ctx, all)
+	require.NoError(t, err)
+
+	var names []string
+	page := restaurant.PageRequest{Limit: 2, SortBy: restaurant.SortByAge, Descending: true}
+	for {
+		p, err := repo.findPage(ctx, all.Matches, page)
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, p.TotalCount)
+		for _, r := range p.Items {
+			names = append(names, r.Name)
+		}
+		if !p.HasMore {
+			break
+		}
+		page.Cursor = p.NextCursor
+	}
+	assert.Equal(t, []string{"Burger Hut", "Apple Cafe", "Noodle Bar"}, names)
+
+	_, err = repo.FindByNamePaged(ctx, "x", restaurant.PageRequest{Cursor: page.Cursor, SortBy: restaurant.SortByName})
+	assert.ErrorIs(t, err, restaurant.ErrInvalidCursor)
+}
+
+func TestRestaurantRepoContract(t *testing.T) {
+	repotest.RunRestaurantRepositorySuite(t, func(t *testing.T) restaurant.RestaurantRepository {
+		return NewRestaurantRepo()
+	})
+}
+
+func TestRestaurantRepoWithTransaction(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRestaurantRepo()
+
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.InsertRestaurant(ctx, repotest.NewRestaurant("r1", "Soup Place")); err != nil {
+			return err
+		}
+		return repo.UpdateMenu(ctx, "missing", nil)
+	})
+	assert.ErrorIs(t, err, restaurant.ErrNotFound)
+	assert.Len(t, repo.Snapshot(), 1, "writes are not rolled back")
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	called := false
+	assert.ErrorIs(t, repo.WithTransaction(cancelled, func(context.Context) error { called = true; return nil }), context.Canceled)
+	assert.False(t, called)
+}
+
+func TestStemEnglish(t *testing.T) {
+	// Expected stems are those of the Snowball english stemmer Mongo uses.
+	for word, want := range map[string]string{
+		"fries": "fri", "fried": "fri", "fry": "fri", "frying": "fri", "ties": "tie",
+		"berries": "berri", "berry": "berri", "burgers": "burger", "tacos": "taco",
+		"dishes": "dish", "classes": "class", "bus": "bus", "glass": "glass", "day": "day",
+		"pizza": "pizza",
+	} {
+		assert.Equal(t, want, stemEnglish(word), word)
+	}
+}
+
+func TestRestaurantRepoSearchTextLanguages(t *testing.T) {
+	ctx := context.Background()
+	repo := NewRestaurantRepo()
+	fries := repotest.NewRestaurant("r1", "Chip Shop")
+	fries.Menu = []restaurant.MenuItem{{Name: "Fries", Description: "Fried twice"}}
+	require.NoError(t, repo.InsertRestaurant(ctx, fries))
+
+	for _, lang := range []string{"", "english", "en"} {
+		found, err := repo.SearchText(ctx, restaurant.TextQuery{Search: "fry", Language: lang})
+		require.NoError(t, err, lang)
+		assert.Len(t, found, 1, lang)
+	}
+	_, err := repo.SearchText(ctx, restaurant.TextQuery{Search: "patatas", Language: "spanish"})
+	assert.ErrorIs(t, err, restaurant.ErrInvalidTextSearch, "languages Mongo supports but the memory repository does not are rejected")
+}
@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+
+	restaurant "github.com/testingrepo/domain"
+)
+
+// textField is one searchable value of a restaurant with its relevance weight.
+type textField struct {
+	text   string
+	weight float64
+}
+
+func textFields(rest *restaurant.Restaurant) []textField {
+	fields := []textField{{rest.Name, restaurant.TextWeightName}}
+	for _, item := range rest.Menu {
+		fields = append(fields,
+			textField{item.Name, restaurant.TextWeightMenuItemName},
+			textField{item.Description, restaurant.TextWeightMenuItemDescription})
+	}
+	return fields
+}
+
+// SearchText follows the Mongo $text rules: a restaurant matches when it contains any
+// term, every phrase and none of the exclusions. Phrase words count as terms, and
+// scores use the weighting formula of Mongo's textScore.
+//
+// It approximates mongo.RestaurantRepo rather than reproducing it: only english and
+// none are supported, and other languages fail with domain.ErrInvalidTextSearch. The
+// english stemmer and stop words are simplified versions of the Snowball ones Mongo
+// uses, so some words stem differently and scores, and occasionally matches, differ.
+func (r *RestaurantRepo) SearchText(ctx context.Context, q restaurant.TextQuery) ([]*restaurant.RestaurantTextMatch, error) {
+	q = q.Normalize()
+	search, err := q.Parse()
+	if err != nil {
+		return nil, err
+	}
+	lang, err := textLanguageFor(q.Language)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	terms := lang.stems(append(slices.Clone(search.Terms), search.Phrases...))
+	negated := lang.stems(search.NegatedTerms)
+
+	r.mu.RLock()
+	results := make([]*restaurant.RestaurantTextMatch, 0)
+	for _, id := range r.order {
+		rest := r.byID[id]
+		if score, ok := textScore(textFields(rest), lang, terms, negated, search); ok {
+			results = append(results, &restaurant.RestaurantTextMatch{Restaurant: rest.Clone(), Score: score})
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortStableFunc(results, func(a, b *restaurant.RestaurantTextMatch) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return results[:min(len(results), q.Limit)], nil
+}
+
+// textScore scores fields against the stemmed terms and reports false when they do not
+// match the search.
+func textScore(fields []textField, lang textLanguage, terms, negated map[string]bool, search restaurant.TextSearch) (float64, bool) {
+	var lowered []string
+	for _, f := range fields {
+		lowered = append(lowered, strings.ToLower(f.text))
+	}
+	containsPhrase := func(phrase string) bool {
+		return slices.ContainsFunc(lowered, func(text string) bool {
+			return strings.Contains(text, strings.ToLower(phrase))
+		})
+	}
+	if slices.ContainsFunc(search.NegatedPhrases, containsPhrase) ||
+		!allFunc(search.Phrases, containsPhrase) {
+		return 0, false
+	}
+
+	var score float64
+	for i, f := range fields {
+		tokens := lang.tokens(lowered[i])
+		counts := make(map[string]int)
+		for _, token := range tokens {
+			if negated[token] {
+				return 0, false
+			}
+			if terms[token] {
+				counts[token]++
+			}
+		}
+		for term, count := range counts {
+			score += fieldTermScore(f, lowered[i], term, count, len(tokens))
+		}
+	}
+	return score, score > 0
+}
+
+// fieldTermScore is Mongo's per-field term score: repeated occurrences add
+// geometrically less, short fields weigh more and a field equal to the term gets a
+// small boost.
+func fieldTermScore(f textField, lowered, term string, count, numTokens int) float64 {
+	freq := 2 - math.Pow(2, -float64(count-1))
+	coeff := 0.5*float64(count)/float64(numTokens) + 0.5
+	adjustment := 1.0
+	if lowered == term {
+		adjustment += 0.1
+	}
+	return f.weight * freq * coeff * adjustment
+}
+
+func allFunc[S ~[]E, E any](s S, f func(E) bool) bool {
+	return !slices.ContainsFunc(s, func(e E) bool { return !f(e) })
+}
+
+// textLanguage tokenizes text for one search language.
+type textLanguage struct {
+	stopWords map[string]bool
+	stem      func(string) string
+}
+
+func textLanguageFor(name string) (textLanguage, error) {
+	switch name {
+	case "english", "en":
+		return textLanguage{stopWords: englishStopWords, stem: stemEnglish}, nil
+	case "none":
+		return textLanguage{stem: func(word string) string { return word }}, nil
+	}
+	return textLanguage{}, fmt.Errorf("%w: language %q is not supported by the memory repository",
+		restaurant.ErrInvalidTextSearch, name)
+}
+
+// tokens splits lowered text into stemmed words, dropping stop words.
+func (l textLanguage) tokens(lowered string) []string {
+	var tokens []string
+	for _, word := range strings.FieldsFunc(lowered, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	}) {
+		if !l.stopWords[word] {
+			tokens = append(tokens, l.stem(word))
+		}
+	}
+	return tokens
+}
+
+func (l textLanguage) stems(words []string) map[string]bool {
+	stems := make(map[string]bool)
+	for _, word := range words {
+		for _, token := range l.tokens(strings.ToLower(word)) {
+			stems[token] = true
+		}
+	}
+	return stems
+}
+
+// englishStopWords is the Snowball english stop word list without the contractions,
+// which the tokenizer splits apart. Mongo's list is similar but not identical.
+var englishStopWords = func() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range strings.Fields(`
+		a about above after again against all am an and any are as at be because been
+		before being below between both but by can cannot could did do does doing down
+		during each few for from further had has have having he her here hers herself him
+		himself his how i if in into is it its itself me more most my myself no nor not of
+		off on once only or other ought our ours ourselves out over own same she should so
+		some such than that the their theirs them themselves then there these they this
+		those through to too under until up very was we were what when where which while
+		who whom why with would you your yours yourself yourselves`) {
+		words[w] = true
+	}
+	return words
+}()
+
+// stemEnglish strips the common plural and verb suffixes and turns a final consonant-y
+// into i, following step 1 of the Snowball english stemmer, so "fries", "fried" and
+// "fry" all become "fri" as in Mongo. The later Snowball steps are left out, so derived
+// forms such as "national" keep their suffix and may not match the stems Mongo indexes.
+func stemEnglish(word string) string {
+	word = stripEnglishSuffix(word)
+	if n := len(word); n > 2 && word[n-1] == 'y' && !strings.ContainsRune("aeiouy", rune(word[n-2])) {
+		return word[:n-1] + "i"
+	}
+	return word
+}
+
+func stripEnglishSuffix(word string) string {
+	switch {
+	case strings.HasSuffix(word, "ies") || strings.HasSuffix(word, "ied"):
+		if len(word) > 4 {
+			return word[:len(word)-2]
+		}
+		return word[:len(word)-1]
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		return word[:len(word)-3]
+	case len(word) > 4 && strings.HasSuffix(word, "ed"):
+		return word[:len(word)-2]
+	case len(word) > 4 && (strings.HasSuffix(word, "ches") || strings.HasSuffix(word, "shes") ||
+		strings.HasSuffix(word, "xes") || strings.HasSuffix(word, "sses")):
+		return word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") &&
+		!strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us"):
+		return word[:len(word)-1]
+	}
+	return word
+}
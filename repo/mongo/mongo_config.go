@@ -0,0 +1,492 @@
+package mongo
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+var ErrInvalidConfig = errors.New("invalid mongo config")
+
+const redacted = "REDACTED"
+
+// MongoConfig holds the configuration for the MongoDB connection.
+type MongoConfig struct {
+	URI      string
+	Database string
+	Timeout  time.Duration
+	// Collections overrides the read/write concerns of individual collections.
+	Collections map[string]CollectionConcerns
+
+	// Pool options; zero values keep the driver defaults.
+	MaxPoolSize            uint64
+	MinPoolSize            uint64
+	MaxConnIdleTime        time.Duration
+	ServerSelectionTimeout time.Duration
+
+	// HealthCheckInterval is how often the server is pinged for Health. Zero disables
+	// the health check.
+	HealthCheckInterval time.Duration
+
+	// Credentials. Prefer loading them with FromSecretsDir or the *_FILE variables
+	// over plain environment variables.
+	Username      string
+	Password      string
+	AuthMechanism string
+	AuthSource    string
+
+	AppName    string
+	ReplicaSet string
+	// ReadPreference is a mode name such as "primary" or "secondaryPreferred".
+	ReadPreference string
+	// Compressors lists the wire compressors in order of preference: snappy, zlib, zstd.
+	Compressors []string
+
+	TLS TLSConfig
+}
+
+type TLSConfig struct {
+	Enabled  bool
+	CAFile   string
+	CertFile string
+	KeyFile  string
+	// InsecureSkipVerify disables certificate verification. Development only.
+	InsecureSkipVerify bool
+}
+
+var (
+	authMechanisms = []string{"SCRAM-SHA-1", "SCRAM-SHA-256", "MONGODB-X509", "MONGODB-AWS", "GSSAPI", "PLAIN"}
+	compressors    = []string{"snappy", "zlib", "zstd"}
+)
+
+// DefaultMongoConfig returns the configuration for a local development server.
+func DefaultMongoConfig() MongoConfig {
+	return MongoConfig{
+		URI:                    "mongodb://localhost:27017",
+		Database:               "Example",
+		Timeout:                30 * time.Second,
+		MaxPoolSize:            100,
+		MaxConnIdleTime:        5 * time.Minute,
+		ServerSelectionTimeout: 30 * time.Second,
+		HealthCheckInterval:    30 * time.Second,
+	}
+}
+
+// ConfigSource populates part of a MongoConfig.
+type ConfigSource func(cfg *MongoConfig) error
+
+// LoadMongoConfig starts from DefaultMongoConfig, applies sources in order, later
+// sources overriding earlier ones, and validates the result.
+func LoadMongoConfig(sources ...ConfigSource) (MongoConfig, error) {
+	cfg := DefaultMongoConfig()
+	for _, src := range sources {
+		if err := src(&cfg); err != nil {
+			return MongoConfig{}, err
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return MongoConfig{}, err
+	}
+	return cfg, nil
+}
+
+// envVars maps the suffix of each supported environment variable to its setter.
+var envVars = map[string]func(cfg *MongoConfig, v string) error{
+	"URI":                      func(cfg *MongoConfig, v string) error { cfg.URI = v; return nil },
+	"DATABASE":                 func(cfg *MongoConfig, v string) error { cfg.Database = v; return nil },
+	"TIMEOUT":                  durationVar(func(cfg *MongoConfig) *time.Duration { return &cfg.Timeout }),
+	"MAX_POOL_SIZE":            uintVar(func(cfg *MongoConfig) *uint64 { return &cfg.MaxPoolSize }),
+	"MIN_POOL_SIZE":            uintVar(func(cfg *MongoConfig) *uint64 { return &cfg.MinPoolSize }),
+	"MAX_CONN_IDLE_TIME":       durationVar(func(cfg *MongoConfig) *time.Duration { return &cfg.MaxConnIdleTime }),
+	"SERVER_SELECTION_TIMEOUT": durationVar(func(cfg *MongoConfig) *time.Duration { return &cfg.ServerSelectionTimeout }),
+	"HEALTH_CHECK_INTERVAL":    durationVar(func(cfg *MongoConfig) *time.Duration { return &cfg.HealthCheckInterval }),
+	"USERNAME":                 func(cfg *MongoConfig, v string) error { cfg.Username = v; return nil },
+	"PASSWORD":                 func(cfg *MongoConfig, v string) error { cfg.Password = v; return nil },
+	"USERNAME_FILE":            secretVar(func(cfg *MongoConfig) *string { return &cfg.Username }),
+	"PASSWORD_FILE":            secretVar(func(cfg *MongoConfig) *string { return &cfg.Password }),
+	"AUTH_MECHANISM":           func(cfg *MongoConfig, v string) error { cfg.AuthMechanism = v; return nil },
+	"AUTH_SOURCE":              func(cfg *MongoConfig, v string) error { cfg.AuthSource = v; return nil },
+	"APP_NAME":                 func(cfg *MongoConfig, v string) error { cfg.AppName = v; return nil },
+	"REPLICA_SET":              func(cfg *MongoConfig, v string) error { cfg.ReplicaSet = v; return nil },
+	"READ_PREFERENCE":          func(cfg *MongoConfig, v string) error { cfg.ReadPreference = v; return nil },
+	"COMPRESSORS":              func(cfg *MongoConfig, v string) error { cfg.Compressors = splitList(v); return nil },
+	"TLS":                      boolVar(func(cfg *MongoConfig) *bool { return &cfg.TLS.Enabled }),
+	"TLS_CA_FILE":              func(cfg *MongoConfig, v string) error { cfg.TLS.CAFile = v; return nil },
+	"TLS_CERT_FILE":            func(cfg *MongoConfig, v string) error { cfg.TLS.CertFile = v; return nil },
+	"TLS_KEY_FILE":             func(cfg *MongoConfig, v string) error { cfg.TLS.KeyFile = v; return nil },
+	"TLS_INSECURE":             boolVar(func(cfg *MongoConfig) *bool { return &cfg.TLS.InsecureSkipVerify }),
+}
+
+// FromEnv reads the variables named prefix + suffix, e.g. MONGO_URI for prefix
+// "MONGO_". USERNAME_FILE and PASSWORD_FILE read the credential from a file.
+// Unset variables leave the config untouched.
+func FromEnv(prefix string) ConfigSource {
+	return func(cfg *MongoConfig) error {
+		var errs []error
+		// Sorted so that X_FILE is applied after, and wins over, X.
+		for _, suffix := range slices.Sorted(maps.Keys(envVars)) {
+			set := envVars[suffix]
+			v, ok := os.LookupEnv(prefix + suffix)
+			if !ok {
+				continue
+			}
+			if err := set(cfg, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s%s: %w", prefix, suffix, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+func durationVar(field func(cfg *MongoConfig) *time.Duration) func(cfg *MongoConfig, v string) error {
+	return func(cfg *MongoConfig, v string) error {
+		d, err := time.ParseDuration(v)
+		if err == nil {
+			*field(cfg) = d
+		}
+		return err
+	}
+}
+
+func uintVar(field func(cfg *MongoConfig) *uint64) func(cfg *MongoConfig, v string) error {
+	return func(cfg *MongoConfig, v string) error {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err == nil {
+			*field(cfg) = n
+		}
+		return err
+	}
+}
+
+func boolVar(field func(cfg *MongoConfig) *bool) func(cfg *MongoConfig, v string) error {
+	return func(cfg *MongoConfig, v string) error {
+		b, err := strconv.ParseBool(v)
+		if err == nil {
+			*field(cfg) = b
+		}
+		return err
+	}
+}
+
+func secretVar(field func(cfg *MongoConfig) *string) func(cfg *MongoConfig, path string) error {
+	return func(cfg *MongoConfig, path string) error {
+		secret, err := readSecret(path)
+		if err == nil {
+			*field(cfg) = secret
+		}
+		return err
+	}
+}
+
+func readSecret(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// FromSecretsDir reads the "username" and "password" files of dir, the layout of a
+// mounted Kubernetes or Docker secret. Missing files are skipped.
+func FromSecretsDir(dir string) ConfigSource {
+	return func(cfg *MongoConfig) error {
+		for name, field := range map[string]*string{"username": &cfg.Username, "password": &cfg.Password} {
+			secret, err := readSecret(filepath.Join(dir, name))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("secret %s: %w", name, err)
+			}
+			*field = secret
+		}
+		return nil
+	}
+}
+
+// fileConfig is the JSON form of MongoConfig. Absent fields leave the config untouched.
+type fileConfig struct {
+	URI                    *string   `json:"uri"`
+	Database               *string   `json:"database"`
+	Timeout                *duration `json:"timeout"`
+	MaxPoolSize            *uint64   `json:"max_pool_size"`
+	MinPoolSize            *uint64   `json:"min_pool_size"`
+	MaxConnIdleTime        *duration `json:"max_conn_idle_time"`
+	ServerSelectionTimeout *duration `json:"server_selection_timeout"`
+	HealthCheckInterval    *duration `json:"health_check_interval"`
+	Username               *string   `json:"username"`
+	AuthMechanism          *string   `json:"auth_mechanism"`
+	AuthSource             *string   `json:"auth_source"`
+	AppName                *string   `json:"app_name"`
+	ReplicaSet             *string   `json:"replica_set"`
+	ReadPreference         *string   `json:"read_preference"`
+	Compressors            []string  `json:"compressors"`
+	TLS                    *struct {
+		Enabled            bool   `json:"enabled"`
+		CAFile             string `json:"ca_file"`
+		CertFile           string `json:"cert_file"`
+		KeyFile            string `json:"key_file"`
+		InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	} `json:"tls"`
+}
+
+// duration reads durations written as strings, e.g. "30s".
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(raw []byte) error {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	*d = duration(v)
+	return err
+}
+
+// FromFile reads a JSON config file. Passwords are not accepted in files; use
+// FromSecretsDir or MONGO_PASSWORD_FILE instead.
+func FromFile(path string) ConfigSource {
+	return func(cfg *MongoConfig) error {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var fc fileConfig
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&fc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fc.apply(cfg)
+		return nil
+	}
+}
+
+func (fc fileConfig) apply(cfg *MongoConfig) {
+	setIf(&cfg.URI, fc.URI)
+	setIf(&cfg.Database, fc.Database)
+	setIf(&cfg.MaxPoolSize, fc.MaxPoolSize)
+	setIf(&cfg.MinPoolSize, fc.MinPoolSize)
+	setIf(&cfg.Username, fc.Username)
+	setIf(&cfg.AuthMechanism, fc.AuthMechanism)
+	setIf(&cfg.AuthSource, fc.AuthSource)
+	setIf(&cfg.AppName, fc.AppName)
+	setIf(&cfg.ReplicaSet, fc.ReplicaSet)
+	setIf(&cfg.ReadPreference, fc.ReadPreference)
+	for field, d := range map[*time.Duration]*duration{
+		&cfg.Timeout:                fc.Timeout,
+		&cfg.MaxConnIdleTime:        fc.MaxConnIdleTime,
+		&cfg.ServerSelectionTimeout: fc.ServerSelectionTimeout,
+		&cfg.HealthCheckInterval:    fc.HealthCheckInterval,
+	} {
+		if d != nil {
+			*field = time.Duration(*d)
+		}
+	}
+	if fc.Compressors != nil {
+		cfg.Compressors = fc.Compressors
+	}
+	if fc.TLS != nil {
+		cfg.TLS = TLSConfig(*fc.TLS)
+	}
+}
+
+func setIf[T any](field *T, v *T) {
+	if v != nil {
+		*field = *v
+	}
+}
+
+// Validate reports every problem of cfg at once, each wrapping ErrInvalidConfig.
+func (cfg MongoConfig) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidConfig}, args...)...))
+	}
+
+	switch {
+	case cfg.URI == "":
+		fail("uri is required")
+	case !strings.HasPrefix(cfg.URI, "mongodb://") && !strings.HasPrefix(cfg.URI, "mongodb+srv://"):
+		fail("uri %s must start with mongodb:// or mongodb+srv://", redactURI(cfg.URI))
+	}
+	if cfg.Timeout <= 0 {
+		fail("timeout must be positive")
+	}
+	if cfg.MaxPoolSize > 0 && cfg.MinPoolSize > cfg.MaxPoolSize {
+		fail("min pool size %d exceeds max pool size %d", cfg.MinPoolSize, cfg.MaxPoolSize)
+	}
+	if cfg.MaxConnIdleTime < 0 || cfg.ServerSelectionTimeout < 0 || cfg.HealthCheckInterval < 0 {
+		fail("durations must not be negative")
+	}
+
+	if cfg.Password != "" && cfg.Username == "" {
+		fail("password set without username")
+	}
+	if cfg.AuthMechanism != "" && !slices.Contains(authMechanisms, cfg.AuthMechanism) {
+		fail("unknown auth mechanism %q, want one of %v", cfg.AuthMechanism, authMechanisms)
+	}
+	if cfg.AuthMechanism == "MONGODB-X509" && (!cfg.TLS.Enabled || cfg.TLS.CertFile == "") {
+		fail("MONGODB-X509 requires TLS with a client certificate")
+	}
+
+	if cfg.ReadPreference != "" {
+		if _, err := readpref.ModeFromString(cfg.ReadPreference); err != nil {
+			fail("read preference: %v", err)
+		}
+	}
+	for _, c := range cfg.Compressors {
+		if !slices.Contains(compressors, c) {
+			fail("unknown compressor %q, want one of %v", c, compressors)
+		}
+	}
+
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		fail("tls cert file and key file must be set together")
+	}
+	if !cfg.TLS.Enabled && (cfg.TLS.CAFile != "" || cfg.TLS.CertFile != "" || cfg.TLS.InsecureSkipVerify) {
+		fail("tls options set but tls is not enabled")
+	}
+	return errors.Join(errs...)
+}
+
+// clientOptions translates cfg into driver options.
+func (cfg MongoConfig) clientOptions() (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(cfg.URI)
+	if cfg.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(cfg.MaxPoolSize)
+	}
+	if cfg.MinPoolSize > 0 {
+		opts.SetMinPoolSize(cfg.MinPoolSize)
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+	}
+	if cfg.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+
+	if cfg.Username != "" || cfg.AuthMechanism != "" {
+		opts.SetAuth(options.Credential{
+			AuthMechanism: cfg.AuthMechanism,
+			AuthSource:    cfg.AuthSource,
+			Username:      cfg.Username,
+			Password:      cfg.Password,
+			PasswordSet:   cfg.Password != "",
+		})
+	}
+	if cfg.AppName != "" {
+		opts.SetAppName(cfg.AppName)
+	}
+	if cfg.ReplicaSet != "" {
+		opts.SetReplicaSet(cfg.ReplicaSet)
+	}
+	if cfg.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(cfg.ReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		rp, err := readpref.New(mode)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetReadPreference(rp)
+	}
+	if len(cfg.Compressors) > 0 {
+		opts.SetCompressors(cfg.Compressors)
+	}
+
+	if cfg.TLS.Enabled {
+		tlsCfg, err := cfg.TLS.load()
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsCfg)
+	}
+	return opts, nil
+}
+
+func (t TLSConfig) load() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: t.InsecureSkipVerify}
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls ca file: %w", err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls ca file %s: no certificates found", t.CAFile)
+		}
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// String renders cfg with the password and any credentials in the URI redacted, so
+// the config can be logged safely.
+func (cfg MongoConfig) String() string {
+	type plain MongoConfig
+	p := plain(cfg)
+	p.URI = redactURI(cfg.URI)
+	if p.Password != "" {
+		p.Password = redacted
+	}
+	return fmt.Sprintf("%+v", p)
+}
+
+// GoString keeps %#v from bypassing the redaction of String.
+func (cfg MongoConfig) GoString() string {
+	return cfg.String()
+}
+
+// redactURI replaces the password in the userinfo of uri. It does not use url.Parse,
+// which rejects the comma separated host lists of replica set URIs.
+func redactURI(uri string) string {
+	scheme, rest, ok := strings.Cut(uri, "://")
+	if !ok {
+		return uri
+	}
+	authority, path, _ := strings.Cut(rest, "/")
+	at := strings.LastIndex(authority, "@")
+	if at < 0 {
+		return uri
+	}
+	user, _, hasPassword := strings.Cut(authority[:at], ":")
+	if !hasPassword {
+		return uri
+	}
+	out := scheme + "://" + user + ":" + redacted + authority[at:]
+	if len(rest) > len(authority) {
+		out += "/" + path
+	}
+	return out
+}
@@ -2,55 +2,147 @@ package mongo
 
 import (
 	"context"
-	"time"
+	"errors"
+	"iter"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
-// MongoConfig holds the configuration for the MongoDB connection.
-type MongoConfig struct {
-	URI      string
-	Database string
-	Timeout  time.Duration
+var ErrNoDatabase = errors.New("mongo client has no database selected")
+
+// CollectionConcerns overrides the client defaults for a single collection. Nil fields
+// inherit the database setting.
+type CollectionConcerns struct {
+	ReadConcern    *readconcern.ReadConcern
+	WriteConcern   *writeconcern.WriteConcern
+	ReadPreference *readpref.ReadPref
 }
 
 // MongoClient wraps the mongo.Client and mongo.Database
 type MongoClient struct {
-	Client *mongo.Client
-	DB     *mongo.Database
+	Client      *mongo.Client
+	DB          *mongo.Database
+	collections map[string]CollectionConcerns
+	lifecycle   *lifecycle
 }
 
 type Database string
 
-// ConnectMongo initializes the MongoDB client and connects to the database
+// ConnectMongo initializes the MongoDB client and connects to the database. The
+// client must be released with Close.
 func ConnectMongo(cfg MongoConfig) (*MongoClient, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
 	defer cancel()
 
-	clientOpts := options.Client().ApplyURI(cfg.URI)
-	client, err := mongo.Connect(ctx, clientOpts)
+	opts, err := cfg.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	if err := client.Ping(ctx, readpref.Primary()); err != nil {
+		_ = client.Disconnect(context.Background())
 		return nil, err
 	}
-	return &MongoClient{Client: client}, nil
+	m := newMongoClient(client, cfg)
+	if cfg.HealthCheckInterval > 0 {
+		m.startHealthCheck(cfg.HealthCheckInterval, cfg.Timeout)
+	}
+	return m, nil
+}
+
+// newMongoClient binds client to the configured database. DB stays nil when no
+// database is configured; operations then fail with ErrNoDatabase until WithDatabase is used.
+func newMongoClient(client *mongo.Client, cfg MongoConfig) *MongoClient {
+	m := &MongoClient{Client: client, collections: cfg.Collections, lifecycle: newLifecycle()}
+	if cfg.Database != "" {
+		m.DB = client.Database(cfg.Database)
+	}
+	return m
+}
+
+// WithDatabase returns a MongoClient bound to database name, sharing the connection
+// pool and collection concerns of m.
+func (m *MongoClient) WithDatabase(name string, opts ...*options.DatabaseOptions) *MongoClient {
+	return &MongoClient{Client: m.Client, DB: m.Client.Database(name, opts...), collections: m.collections, lifecycle: m.lifecycle}
+}
+
+// WithCollectionConcerns returns a copy of m using c for collection coll.
+func (m *MongoClient) WithCollectionConcerns(coll string, c CollectionConcerns) *MongoClient {
+	collections := make(map[string]CollectionConcerns, len(m.collections)+1)
+	for k, v := range m.collections {
+		collections[k] = v
+	}
+	collections[coll] = c
+	return &MongoClient{Client: m.Client, DB: m.DB, collections: collections, lifecycle: m.lifecycle}
+}
+
+// Collection returns a handle to coll in the bound database, with its configured concerns.
+func (m *MongoClient) Collection(coll string) (*mongo.Collection, error) {
+	if m.DB == nil {
+		return nil, ErrNoDatabase
+	}
+	return m.DB.Collection(coll, m.collectionOptions(coll)), nil
+}
+
+// collection returns the collection handle of an operation, registered as in flight
+// until release is called.
+func (m *MongoClient) collection(coll string) (*mongo.Collection, func(), error) {
+	release, err := m.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+	collection, err := m.Collection(coll)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+	return collection, release, nil
+}
+
+func (m *MongoClient) collectionOptions(coll string) *options.CollectionOptions {
+	opts := options.Collection()
+	c, ok := m.collections[coll]
+	if !ok {
+		return opts
+	}
+	if c.ReadConcern != nil {
+		opts.SetReadConcern(c.ReadConcern)
+	}
+	if c.WriteConcern != nil {
+		opts.SetWriteConcern(c.WriteConcern)
+	}
+	if c.ReadPreference != nil {
+		opts.SetReadPreference(c.ReadPreference)
+	}
+	return opts
 }
 
 // FindOne executes a find one operation
 func (m *MongoClient) FindOne(ctx context.Context, coll string, filter any, result any) error {
-	collection := m.DB.Collection(coll)
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return err
+	}
+	defer release()
 	return collection.FindOne(ctx, filter).Decode(result)
 }
 
 // FindMany executes a find many operation
-func (m *MongoClient) FindMany(ctx context.Context, coll string, filter any, results any) error {
-	collection := m.DB.Collection(coll)
-	cursor, err := collection.Find(ctx, filter)
+func (m *MongoClient) FindMany(ctx context.Context, coll string, filter any, results any, opts ...*options.FindOptions) error {
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return err
+	}
+	defer release()
+	cursor, err := collection.Find(ctx, filter, opts...)
 	if err != nil {
 		return err
 	}
@@ -58,32 +150,160 @@ func (m *MongoClient) FindMany(ctx context.Context, coll string, filter any, res
 	return cursor.All(ctx, results)
 }
 
+// StreamMany executes a find and yields the documents one at a time as the cursor
+// decodes them, without buffering the result set.
+func StreamMany[T any](ctx context.Context, m *MongoClient, coll string, filter any, opts ...*options.FindOptions) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		collection, release, err := m.collection(coll)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer release()
+		cursor, err := collection.Find(ctx, filter, opts...)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer cursor.Close(ctx)
+
+		for cursor.Next(ctx) {
+			doc := new(T)
+			if err := cursor.Decode(doc); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(doc, nil) {
+				return
+			}
+		}
+		if err := cursor.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// Aggregate runs pipeline and decodes every resulting document into results
+func (m *MongoClient) Aggregate(ctx context.Context, coll string, pipeline any, results any, opts ...*options.AggregateOptions) error {
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return err
+	}
+	defer release()
+	cursor, err := collection.Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
+
+// CreateIndexes creates the indexes of models, returning their names. Existing
+// indexes with the same definition are left as they are.
+func (m *MongoClient) CreateIndexes(ctx context.Context, coll string, models []mongo.IndexModel) ([]string, error) {
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return collection.Indexes().CreateMany(ctx, models)
+}
+
+// IndexNames lists the names of the indexes of coll. A missing collection has none.
+func (m *MongoClient) IndexNames(ctx context.Context, coll string) ([]string, error) {
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	specs, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(specs))
+	for i, spec := range specs {
+		names[i] = spec.Name
+	}
+	return names, nil
+}
+
+// DropIndex drops the index called name
+func (m *MongoClient) DropIndex(ctx context.Context, coll string, name string) error {
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return err
+	}
+	defer release()
+	_, err = collection.Indexes().DropOne(ctx, name)
+	return err
+}
+
+// CountDocuments counts the documents matching filter
+func (m *MongoClient) CountDocuments(ctx context.Context, coll string, filter any) (int64, error) {
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	return collection.CountDocuments(ctx, filter)
+}
+
 // InsertOne inserts a single document
 func (m *MongoClient) InsertOne(ctx context.Context, coll string, document any) (*mongo.InsertOneResult, error) {
-	collection := m.DB.Collection(coll)
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return collection.InsertOne(ctx, document)
 }
 
 // InsertMany inserts multiple documents
 func (m *MongoClient) InsertMany(ctx context.Context, coll string, documents []any) (*mongo.InsertManyResult, error) {
-	collection := m.DB.Collection(coll)
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return collection.InsertMany(ctx, documents)
 }
 
 // UpdateOne performs an update on a single document
 func (m *MongoClient) UpdateOne(ctx context.Context, coll string, filter any, update any) (*mongo.UpdateResult, error) {
-	collection := m.DB.Collection(coll)
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return collection.UpdateOne(ctx, filter, update)
 }
 
+// UpdateMany performs an update on every document matching filter
+func (m *MongoClient) UpdateMany(ctx context.Context, coll string, filter any, update any) (*mongo.UpdateResult, error) {
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return collection.UpdateMany(ctx, filter, update)
+}
+
 // DeleteOne removes a single document
 func (m *MongoClient) DeleteOne(ctx context.Context, coll string, filter any) (*mongo.DeleteResult, error) {
-	collection := m.DB.Collection(coll)
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return collection.DeleteOne(ctx, filter)
 }
 
 // DeleteMany removes multiple documents
 func (m *MongoClient) DeleteMany(ctx context.Context, coll string, filter any) (*mongo.DeleteResult, error) {
-	collection := m.DB.Collection(coll)
+	collection, release, err := m.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
 	return collection.DeleteMany(ctx, filter)
 }
@@ -0,0 +1,105 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+// newOfflineClient returns a driver client that never dials: the driver connects
+// lazily, so handles and options can be inspected without a server.
+func newOfflineClient(t *testing.T) *mongo.Client {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client
+}
+
+func TestNewMongoClientBindsDatabase(t *testing.T) {
+	client := newOfflineClient(t)
+
+	m := newMongoClient(client, MongoConfig{Database: "restaurants"})
+	require.NotNil(t, m.DB)
+	assert.Equal(t, "restaurants", m.DB.Name())
+
+	other := m.WithDatabase("archive")
+	assert.Equal(t, "archive", other.DB.Name())
+	assert.Same(t, m.Client, other.Client)
+	assert.Equal(t, "restaurants", m.DB.Name())
+
+	coll, err := other.Collection("restaurants")
+	require.NoError(t, err)
+	assert.Equal(t, "archive.restaurants", coll.Database().Name()+"."+coll.Name())
+}
+
+func TestMongoClientWithoutDatabase(t *testing.T) {
+	m := newMongoClient(newOfflineClient(t), MongoConfig{})
+	ctx := context.Background()
+
+	_, err := m.Collection("restaurants")
+	assert.ErrorIs(t, err, ErrNoDatabase)
+	assert.ErrorIs(t, m.FindOne(ctx, "restaurants", bson.M{}, &bson.M{}), ErrNoDatabase)
+	_, err = m.InsertOne(ctx, "restaurants", bson.M{})
+	assert.ErrorIs(t, err, ErrNoDatabase)
+	for _, err := range StreamMany[bson.M](ctx, m, "restaurants", bson.M{}) {
+		assert.ErrorIs(t, err, ErrNoDatabase)
+	}
+}
+
+func TestMongoClientCollectionConcerns(t *testing.T) {
+	majority := writeconcern.Majority()
+	m := newMongoClient(newOfflineClient(t), MongoConfig{
+		Database: "restaurants",
+		Collections: map[string]CollectionConcerns{
+			"restaurants": {ReadConcern: readconcern.Majority(), WriteConcern: majority},
+		},
+	})
+	audit := m.WithCollectionConcerns("audit", CollectionConcerns{WriteConcern: writeconcern.W1()})
+
+	opts := m.collectionOptions("restaurants")
+	assert.Equal(t, readconcern.Majority(), opts.ReadConcern)
+	assert.Same(t, majority, opts.WriteConcern)
+	assert.Nil(t, m.collectionOptions("audit").WriteConcern)
+	assert.Equal(t, writeconcern.W1(), audit.collectionOptions("audit").WriteConcern)
+	assert.Same(t, majority, audit.collectionOptions("restaurants").WriteConcern)
+}
+
+func TestConnectMongoBindsDatabase(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	ctx := context.Background()
+	name := fmt.Sprintf("crudtest_%d", time.Now().UnixNano())
+	m, err := ConnectMongo(MongoConfig{URI: uri, Database: name, Timeout: 10 * time.Second})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = m.Close(ctx) })
+	other := m.WithDatabase(name + "_other")
+	t.Cleanup(func() {
+		_ = m.DB.Drop(ctx)
+		_ = other.DB.Drop(ctx)
+	})
+
+	_, err = m.InsertOne(ctx, "items", bson.M{"_id": "a"})
+	require.NoError(t, err)
+	_, err = other.InsertOne(ctx, "items", bson.M{"_id": "b"})
+	require.NoError(t, err)
+
+	var got bson.M
+	require.NoError(t, m.FindOne(ctx, "items", bson.M{}, &got))
+	assert.Equal(t, "a", got["_id"])
+	n, err := other.CountDocuments(ctx, "items", bson.M{"_id": "a"})
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
@@ -0,0 +1,146 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+var ErrClientClosed = errors.New("mongo client closed")
+
+// HealthStatus is the result of the latest periodic ping.
+type HealthStatus struct {
+	Healthy             bool
+	LastCheck           time.Time
+	LastError           error
+	ConsecutiveFailures int
+}
+
+// lifecycle is shared by a MongoClient and the handles derived from it with
+// WithDatabase and WithCollectionConcerns, so closing any of them closes the connection.
+type lifecycle struct {
+	mu       sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+	health   HealthStatus
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{health: HealthStatus{Healthy: true, LastCheck: time.Now()}}
+}
+
+// acquire registers an in-flight operation. The returned func must be called once the
+// operation is finished.
+func (m *MongoClient) acquire() (func(), error) {
+	lc := m.lifecycle
+	if lc == nil {
+		return func() {}, nil
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.closed {
+		return nil, ErrClientClosed
+	}
+	lc.inFlight.Add(1)
+	return lc.inFlight.Done, nil
+}
+
+// Close stops accepting operations, waits for the in-flight ones to finish and
+// disconnects. If ctx expires first the client is disconnected anyway, aborting the
+// remaining operations, and the context error is returned.
+func (m *MongoClient) Close(ctx context.Context) error {
+	lc := m.lifecycle
+	if lc == nil {
+		return m.Client.Disconnect(ctx)
+	}
+	lc.mu.Lock()
+	if lc.closed {
+		lc.mu.Unlock()
+		return ErrClientClosed
+	}
+	lc.closed = true
+	lc.mu.Unlock()
+
+	if lc.stop != nil {
+		close(lc.stop)
+		<-lc.done
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		lc.inFlight.Wait()
+		close(drained)
+	}()
+	var waitErr error
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		waitErr = ctx.Err()
+	}
+
+	// Disconnect needs a live context to end sessions cleanly, even when ctx expired.
+	disconnectCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	return errors.Join(waitErr, m.Client.Disconnect(disconnectCtx))
+}
+
+// Health returns the status of the latest health check. Without a health check
+// interval the client is reported healthy until closed.
+func (m *MongoClient) Health() HealthStatus {
+	lc := m.lifecycle
+	if lc == nil {
+		return HealthStatus{Healthy: true}
+	}
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	if lc.closed {
+		return HealthStatus{LastCheck: lc.health.LastCheck, LastError: ErrClientClosed}
+	}
+	return lc.health
+}
+
+// startHealthCheck pings the primary every interval. The driver re-establishes dropped
+// connections by itself; the health check reports when the server becomes unreachable
+// and when it is back.
+func (m *MongoClient) startHealthCheck(interval, timeout time.Duration) {
+	lc := m.lifecycle
+	lc.stop = make(chan struct{})
+	lc.done = make(chan struct{})
+	go func() {
+		defer close(lc.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-lc.stop:
+				return
+			case <-ticker.C:
+				m.checkHealth(timeout)
+			}
+		}
+	}()
+}
+
+func (m *MongoClient) checkHealth(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := m.Client.Ping(ctx, readpref.Primary())
+
+	lc := m.lifecycle
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+	lc.health.LastCheck = time.Now()
+	lc.health.LastError = err
+	lc.health.Healthy = err == nil
+	if err != nil {
+		lc.health.ConsecutiveFailures++
+	} else {
+		lc.health.ConsecutiveFailures = 0
+	}
+}
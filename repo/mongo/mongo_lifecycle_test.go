@@ -0,0 +1,85 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newOfflineMongoClient(t *testing.T) *MongoClient {
+	t.Helper()
+	cfg := MongoConfig{URI: "mongodb://127.0.0.1:1", Database: "restaurants", ServerSelectionTimeout: 50 * time.Millisecond}
+	opts, err := cfg.clientOptions()
+	require.NoError(t, err)
+	client, err := mongo.Connect(context.Background(), opts)
+	require.NoError(t, err)
+	return newMongoClient(client, cfg)
+}
+
+func TestMongoClientCloseDrainsInFlight(t *testing.T) {
+	m := newOfflineMongoClient(t)
+	release, err := m.acquire()
+	require.NoError(t, err)
+
+	closed := make(chan error, 1)
+	go func() { closed <- m.Close(context.Background()) }()
+
+	require.Eventually(t, func() bool {
+		return m.Health().LastError == ErrClientClosed
+	}, time.Second, time.Millisecond)
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned before the in-flight operation finished: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	release()
+	assert.NoError(t, <-closed)
+	_, err = m.InsertOne(context.Background(), "restaurants", bson.M{})
+	assert.ErrorIs(t, err, ErrClientClosed)
+	assert.ErrorIs(t, m.WithDatabase("other").FindOne(context.Background(), "restaurants", bson.M{}, &bson.M{}), ErrClientClosed)
+	assert.ErrorIs(t, m.Close(context.Background()), ErrClientClosed)
+	assert.False(t, m.Health().Healthy)
+}
+
+func TestMongoClientCloseTimeout(t *testing.T) {
+	m := newOfflineMongoClient(t)
+	_, err := m.acquire()
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, m.Close(ctx), context.DeadlineExceeded)
+}
+
+func TestMongoClientHealthCheck(t *testing.T) {
+	m := newOfflineMongoClient(t)
+	defer m.Close(context.Background())
+	assert.True(t, m.Health().Healthy)
+
+	m.startHealthCheck(time.Millisecond, 20*time.Millisecond)
+	require.Eventually(t, func() bool {
+		return m.Health().ConsecutiveFailures >= 2
+	}, 2*time.Second, 5*time.Millisecond)
+	h := m.Health()
+	assert.False(t, h.Healthy)
+	assert.Error(t, h.LastError)
+}
+
+func TestMongoConfigClientOptions(t *testing.T) {
+	cfg := DefaultMongoConfig()
+	cfg.MinPoolSize = 5
+	opts, err := cfg.clientOptions()
+	require.NoError(t, err)
+
+	assert.Equal(t, uint64(100), *opts.MaxPoolSize)
+	assert.Equal(t, uint64(5), *opts.MinPoolSize)
+	assert.Equal(t, 5*time.Minute, *opts.MaxConnIdleTime)
+	assert.Equal(t, 30*time.Second, *opts.ServerSelectionTimeout)
+	assert.Equal(t, []string{"localhost:27017"}, opts.Hosts)
+}
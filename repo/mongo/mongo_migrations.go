@@ -0,0 +1,447 @@
+package mongo
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Collections used by the Migrator
+var (
+	MIGRATION_COLLECTION      = "Migrations"
+	MIGRATION_LOCK_COLLECTION = "MigrationLocks"
+)
+
+// LatestVersion makes Migrator.Up apply every pending migration.
+const LatestVersion = math.MaxInt
+
+var (
+	ErrMigrationLocked  = errors.New("migrations are locked by another instance")
+	ErrInvalidMigration = errors.New("invalid migration")
+)
+
+// MigrationStep is one change of a migration. Steps should be idempotent: a failed
+// migration is not recorded, so its steps run again on the next attempt.
+type MigrationStep interface {
+	// Plan describes what Apply would change, without changing anything.
+	Plan(ctx context.Context, db *MongoClient) (string, error)
+	Apply(ctx context.Context, db *MongoClient) error
+}
+
+// Migration is a versioned set of steps. Down undoes Up; a migration without Down
+// steps is reverted by forgetting it.
+type Migration struct {
+	Version     int
+	Description string
+	Up          []MigrationStep
+	Down        []MigrationStep
+}
+
+// MigrationResult lists the changes of one migration, as applied or as planned.
+type MigrationResult struct {
+	Version     int
+	Description string
+	Changes     []string
+}
+
+// MigrationReport is the outcome of Migrator.Up or Migrator.Down.
+type MigrationReport struct {
+	Direction string
+	DryRun    bool
+	// From and To are the schema versions before and after the run. A dry run changes
+	// nothing, so To is From.
+	From, To   int
+	Migrations []MigrationResult
+}
+
+type migrationRecordBSON struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+type migrationLockBSON struct {
+	ID        string    `bson:"_id"`
+	Owner     string    `bson:"owner"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// MigratorOption customises a Migrator.
+type MigratorOption func(m *Migrator)
+
+// WithDryRun makes Up and Down report the planned changes without applying them or
+// taking the lock. Every step is planned against the current data, so a plan does not
+// account for the steps before it.
+func WithDryRun() MigratorOption {
+	return func(m *Migrator) {
+		m.dryRun = true
+	}
+}
+
+// WithLockTTL sets how long the lock outlives its last renewal before another instance
+// may take it over, e.g. after a crash. The lock is renewed every third of the TTL while
+// migrations run. Defaults to 10 minutes.
+func WithLockTTL(ttl time.Duration) MigratorOption {
+	return func(m *Migrator) {
+		m.lockTTL = ttl
+	}
+}
+
+// Migrator applies migrations in version order and records them in
+// MIGRATION_COLLECTION. A lock document in MIGRATION_LOCK_COLLECTION ensures only one
+// instance migrates at a time.
+type Migrator struct {
+	db         *MongoClient
+	migrations []Migration
+	dryRun     bool
+	lockTTL    time.Duration
+	owner      string
+	now        func() time.Time
+}
+
+// NewMigrator validates migrations: versions must be positive and unique. The lock TTL
+// must be positive.
+func NewMigrator(db *MongoClient, migrations []Migration, opts ...MigratorOption) (*Migrator, error) {
+	sorted := slices.Clone(migrations)
+	slices.SortFunc(sorted, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	for i, mig := range sorted {
+		if mig.Version <= 0 {
+			return nil, fmt.Errorf("%w: version %d must be positive", ErrInvalidMigration, mig.Version)
+		}
+		if i > 0 && sorted[i-1].Version == mig.Version {
+			return nil, fmt.Errorf("%w: duplicate version %d", ErrInvalidMigration, mig.Version)
+		}
+	}
+
+	m := &Migrator{db: db, migrations: sorted, lockTTL: 10 * time.Minute, owner: newLockOwner(), now: time.Now}
+	for _, opt := range opts {
+		opt(m)
+	}
+	if m.lockTTL <= 0 {
+		return nil, fmt.Errorf("%w: lock TTL %s must be positive", ErrInvalidMigration, m.lockTTL)
+	}
+	return m, nil
+}
+
+func newLockOwner() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Applied returns the recorded versions in ascending order.
+func (m *Migrator) Applied(ctx context.Context) ([]int, error) {
+	var records []migrationRecordBSON
+	if err := m.db.FindMany(ctx, MIGRATION_COLLECTION, bson.M{}, &records); err != nil {
+		return nil, err
+	}
+	versions := make([]int, len(records))
+	for i, rec := range records {
+		versions[i] = rec.Version
+	}
+	slices.Sort(versions)
+	return versions, nil
+}
+
+// Up applies the pending migrations up to and including target, in ascending order.
+// Pending migrations older than the latest applied one, e.g. from a merged branch, are
+// applied too.
+func (m *Migrator) Up(ctx context.Context, target int) (*MigrationReport, error) {
+	return m.run(ctx, "up", func(applied []int) []Migration {
+		var pending []Migration
+		for _, mig := range m.migrations {
+			if mig.Version <= target && !slices.Contains(applied, mig.Version) {
+				pending = append(pending, mig)
+			}
+		}
+		return pending
+	})
+}
+
+// Down reverts the applied migrations newer than target, in descending order. Down(ctx, 0)
+// reverts everything.
+func (m *Migrator) Down(ctx context.Context, target int) (*MigrationReport, error) {
+	return m.run(ctx, "down", func(applied []int) []Migration {
+		var revert []Migration
+		for _, mig := range slices.Backward(m.migrations) {
+			if mig.Version > target && slices.Contains(applied, mig.Version) {
+				revert = append(revert, mig)
+			}
+		}
+		return revert
+	})
+}
+
+func (m *Migrator) run(ctx context.Context, direction string, selectMigrations func(applied []int) []Migration) (*MigrationReport, error) {
+	if !m.dryRun {
+		if err := m.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer m.unlock(context.WithoutCancel(ctx))
+		var stop func()
+		ctx, stop = m.heartbeat(ctx)
+		defer stop()
+	}
+
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range applied {
+		if !slices.ContainsFunc(m.migrations, func(mig Migration) bool { return mig.Version == v }) {
+			return nil, fmt.Errorf("%w: version %d is applied but unknown", ErrInvalidMigration, v)
+		}
+	}
+
+	report := &MigrationReport{Direction: direction, DryRun: m.dryRun, From: latest(applied)}
+	report.To = report.From
+	for _, mig := range selectMigrations(applied) {
+		if !m.dryRun {
+			if err := m.renewLock(ctx); err != nil {
+				return report, err
+			}
+		}
+		result, err := m.migrate(ctx, mig, direction)
+		report.Migrations = append(report.Migrations, result)
+		if err != nil {
+			if cause := context.Cause(ctx); errors.Is(cause, ErrMigrationLocked) {
+				err = cause
+			}
+			return report, fmt.Errorf("migration %d (%s) %s: %w", mig.Version, mig.Description, direction, err)
+		}
+		if m.dryRun {
+			continue
+		}
+		if direction == "up" {
+			applied = append(applied, mig.Version)
+		} else {
+			applied = slices.DeleteFunc(applied, func(v int) bool { return v == mig.Version })
+		}
+		report.To = latest(applied)
+	}
+	return report, nil
+}
+
+// migrate plans or applies the steps of mig in direction and records the outcome.
+func (m *Migrator) migrate(ctx context.Context, mig Migration, direction string) (MigrationResult, error) {
+	steps := mig.Up
+	if direction == "down" {
+		steps = mig.Down
+	}
+	result := MigrationResult{Version: mig.Version, Description: mig.Description}
+	for _, step := range steps {
+		change, err := step.Plan(ctx, m.db)
+		if err != nil {
+			return result, err
+		}
+		if !m.dryRun {
+			if err := step.Apply(ctx, m.db); err != nil {
+				return result, err
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	if m.dryRun {
+		return result, nil
+	}
+
+	if direction == "down" {
+		_, err := m.db.DeleteOne(ctx, MIGRATION_COLLECTION, bson.M{"_id": mig.Version})
+		return result, err
+	}
+	_, err := m.db.InsertOne(ctx, MIGRATION_COLLECTION, migrationRecordBSON{
+		Version:     mig.Version,
+		Description: mig.Description,
+		AppliedAt:   m.now().UTC(),
+	})
+	return result, err
+}
+
+func latest(applied []int) int {
+	if len(applied) == 0 {
+		return 0
+	}
+	return slices.Max(applied)
+}
+
+const migrationLockID = "migrations"
+
+// lock takes the migration lock, taking over a lock whose holder let it expire.
+func (m *Migrator) lock(ctx context.Context) error {
+	now := m.now().UTC()
+	_, err := m.db.InsertOne(ctx, MIGRATION_LOCK_COLLECTION, migrationLockBSON{
+		ID:        migrationLockID,
+		Owner:     m.owner,
+		ExpiresAt: now.Add(m.lockTTL),
+	})
+	if !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	res, err := m.db.UpdateOne(ctx, MIGRATION_LOCK_COLLECTION,
+		bson.M{"_id": migrationLockID, "expires_at": bson.M{"$lt": now}},
+		bson.M{"$set": bson.M{"owner": m.owner, "expires_at": now.Add(m.lockTTL)}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrMigrationLocked
+	}
+	return nil
+}
+
+// renewLock extends the lock, failing with ErrMigrationLocked when another instance
+// took it over.
+func (m *Migrator) renewLock(ctx context.Context) error {
+	res, err := m.db.UpdateOne(ctx, MIGRATION_LOCK_COLLECTION,
+		bson.M{"_id": migrationLockID, "owner": m.owner},
+		bson.M{"$set": bson.M{"expires_at": m.now().UTC().Add(m.lockTTL)}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("%w: the lock was taken over", ErrMigrationLocked)
+	}
+	return nil
+}
+
+// heartbeat renews the lock every third of its TTL until stop is called. The returned
+// context is cancelled with ErrMigrationLocked when the lock is lost, so a running step
+// stops early. Other renewal errors are left to the next renewal.
+func (m *Migrator) heartbeat(ctx context.Context) (_ context.Context, stop func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(m.lockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.renewLock(ctx); errors.Is(err, ErrMigrationLocked) {
+					cancel(err)
+					return
+				}
+			}
+		}
+	}()
+	return ctx, func() {
+		close(done)
+		<-stopped
+		cancel(nil)
+	}
+}
+
+func (m *Migrator) unlock(ctx context.Context) {
+	_, _ = m.db.DeleteOne(ctx, MIGRATION_LOCK_COLLECTION, bson.M{"_id": migrationLockID, "owner": m.owner})
+}
+
+// CreateIndex creates model on coll. The index must be named so it can be planned and
+// dropped.
+func CreateIndex(coll string, model mongo.IndexModel) MigrationStep {
+	return createIndexStep{coll: coll, model: model}
+}
+
+type createIndexStep struct {
+	coll  string
+	model mongo.IndexModel
+}
+
+func (s createIndexStep) name() (string, error) {
+	if s.model.Options == nil || s.model.Options.Name == nil {
+		return "", fmt.Errorf("%w: index on %s has no name", ErrInvalidMigration, s.coll)
+	}
+	return *s.model.Options.Name, nil
+}
+
+func (s createIndexStep) Plan(ctx context.Context, db *MongoClient) (string, error) {
+	name, err := s.name()
+	if err != nil {
+		return "", err
+	}
+	names, err := db.IndexNames(ctx, s.coll)
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(names, name) {
+		return fmt.Sprintf("index %s on %s already exists", name, s.coll), nil
+	}
+	return fmt.Sprintf("create index %s on %s", name, s.coll), nil
+}
+
+func (s createIndexStep) Apply(ctx context.Context, db *MongoClient) error {
+	_, err := db.CreateIndexes(ctx, s.coll, []mongo.IndexModel{s.model})
+	return err
+}
+
+// DropIndex drops the index called name from coll, if it exists.
+func DropIndex(coll, name string) MigrationStep {
+	return dropIndexStep{coll: coll, name: name}
+}
+
+type dropIndexStep struct {
+	coll, name string
+}
+
+func (s dropIndexStep) Plan(ctx context.Context, db *MongoClient) (string, error) {
+	names, err := db.IndexNames(ctx, s.coll)
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains(names, s.name) {
+		return fmt.Sprintf("index %s on %s does not exist", s.name, s.coll), nil
+	}
+	return fmt.Sprintf("drop index %s on %s", s.name, s.coll), nil
+}
+
+func (s dropIndexStep) Apply(ctx context.Context, db *MongoClient) error {
+	names, err := db.IndexNames(ctx, s.coll)
+	if err != nil || !slices.Contains(names, s.name) {
+		return err
+	}
+	return db.DropIndex(ctx, s.coll, s.name)
+}
+
+// RenameField renames the document path from to to in every document of coll that has
+// it. Like $rename, it cannot rename fields inside arrays.
+func RenameField(coll, from, to string) MigrationStep {
+	return Backfill(coll, fmt.Sprintf("rename %s to %s", from, to),
+		bson.M{from: bson.M{"$exists": true}},
+		bson.M{"$rename": bson.M{from: to}})
+}
+
+// Backfill applies update to the documents of coll matching filter. The filter should
+// exclude documents already updated, so the step is idempotent.
+func Backfill(coll, description string, filter, update any) MigrationStep {
+	return backfillStep{coll: coll, description: description, filter: filter, update: update}
+}
+
+type backfillStep struct {
+	coll, description string
+	filter, update    any
+}
+
+func (s backfillStep) Plan(ctx context.Context, db *MongoClient) (string, error) {
+	n, err := db.CountDocuments(ctx, s.coll, s.filter)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s in %d documents of %s", s.description, n, s.coll), nil
+}
+
+func (s backfillStep) Apply(ctx context.Context, db *MongoClient) error {
+	_, err := db.UpdateMany(ctx, s.coll, s.filter, s.update)
+	return err
+}
@@ -0,0 +1,230 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestNewMigratorValidatesVersions(t *testing.T) {
+	_, err := NewMigrator(nil, []Migration{{Version: 2}, {Version: 1}, {Version: 2}})
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+	assert.ErrorContains(t, err, "duplicate version 2")
+
+	_, err = NewMigrator(nil, []Migration{{Version: 0}})
+	assert.ErrorContains(t, err, "version 0 must be positive")
+
+	_, err = NewMigrator(nil, nil, WithLockTTL(0))
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+
+	m, err := NewMigrator(nil, RestaurantMigrations())
+	require.NoError(t, err)
+	for i, mig := range m.migrations {
+		assert.Equal(t, i+1, mig.Version, "restaurant migrations are numbered without gaps")
+	}
+
+	_, err = CreateIndex(RESTAURANT_COLLECTION, mongo.IndexModel{Keys: bson.D{{Key: fieldName, Value: 1}}}).Plan(context.Background(), nil)
+	assert.ErrorIs(t, err, ErrInvalidMigration)
+}
+
+func TestMigratorUpDown(t *testing.T) {
+	repo := newTestRepo(t, newTestClient(t))
+	db := repo.Database
+	ctx := context.Background()
+	_, err := db.InsertMany(ctx, RESTAURANT_COLLECTION, []any{
+		bson.M{"_id": "r1", "name": "Legacy", "ratings": nil, "chef": "Carl"},
+		bson.M{"_id": "r2", "name": "Current", "ratings": bson.A{}},
+	})
+	require.NoError(t, err)
+
+	nameIndex := mongo.IndexModel{Keys: bson.D{{Key: fieldName, Value: 1}}, Options: options.Index().SetName("name_1")}
+	migrations := append(RestaurantMigrations(), Migration{
+		Version:     4,
+		Description: "rename chef",
+		Up:          []MigrationStep{CreateIndex(RESTAURANT_COLLECTION, nameIndex), RenameField(RESTAURANT_COLLECTION, "chef", "head_chef")},
+		Down:        []MigrationStep{RenameField(RESTAURANT_COLLECTION, "head_chef", "chef"), DropIndex(RESTAURANT_COLLECTION, "name_1")},
+	})
+
+	dry, err := NewMigrator(db, migrations, WithDryRun())
+	require.NoError(t, err)
+	report, err := dry.Up(ctx, LatestVersion)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 0, report.To, "a dry run records nothing")
+	require.Len(t, report.Migrations, 4)
+	assert.Contains(t, report.Migrations[2].Changes, "set ratings to [] in 1 documents of Restaurant")
+	assert.Equal(t, []string{"create index name_1 on Restaurant", "rename chef to head_chef in 1 documents of Restaurant"}, report.Migrations[3].Changes)
+	applied, err := dry.Applied(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	m, err := NewMigrator(db, migrations)
+	require.NoError(t, err)
+	report, err = m.Up(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.To)
+	var legacy bson.M
+	require.NoError(t, db.FindOne(ctx, RESTAURANT_COLLECTION, idFilter("r1"), &legacy))
+	assert.Equal(t, bson.A{}, legacy["ratings"])
+
+	report, err = m.Up(ctx, LatestVersion)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.From)
+	assert.Equal(t, 4, report.To)
+	require.NoError(t, db.FindOne(ctx, RESTAURANT_COLLECTION, idFilter("r1"), &legacy))
+	assert.Equal(t, "Carl", legacy["head_chef"])
+
+	report, err = m.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.To)
+	names, err := db.IndexNames(ctx, RESTAURANT_COLLECTION)
+	require.NoError(t, err)
+	assert.Contains(t, names, "address_location_2dsphere")
+	assert.NotContains(t, names, "name_1")
+	assert.NotContains(t, names, "restaurant_text")
+	require.NoError(t, db.FindOne(ctx, RESTAURANT_COLLECTION, idFilter("r1"), &legacy))
+	assert.Equal(t, "Carl", legacy["chef"])
+}
+
+func TestMigratorLock(t *testing.T) {
+	repo := newTestRepo(t, newTestClient(t))
+	ctx := context.Background()
+	holder, err := NewMigrator(repo.Database, nil, WithLockTTL(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, holder.lock(ctx))
+
+	other, err := NewMigrator(repo.Database, RestaurantMigrations())
+	require.NoError(t, err)
+	_, err = other.Up(ctx, LatestVersion)
+	assert.ErrorIs(t, err, ErrMigrationLocked)
+
+	// An expired lock is taken over, and its former holder can no longer renew it.
+	other.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = other.Up(ctx, LatestVersion)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, holder.renewLock(ctx), ErrMigrationLocked)
+}
+
+// The tests below run against the mock deployment, so they check the commands the
+// Migrator sends rather than their effect.
+
+var (
+	mockOK        = bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+	mockUnmatched = bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}}
+)
+
+func mockCursor(mt *mtest.T, docs ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, mt.DB.Name()+".mock", mtest.FirstBatch, docs...)
+}
+
+// sentCommands drains the recorded commands and returns their names.
+func sentCommands(mt *mtest.T) []string {
+	var names []string
+	for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+		names = append(names, e.CommandName)
+	}
+	return names
+}
+
+func backfillMigration(version int) Migration {
+	return Migration{
+		Version:     version,
+		Description: fmt.Sprintf("backfill %d", version),
+		Up:          []MigrationStep{Backfill(RESTAURANT_COLLECTION, "set age", bson.M{fieldAge: nil}, bson.M{"$set": bson.M{fieldAge: 0}})},
+	}
+}
+
+// blockingStep waits in Apply until its context is done.
+type blockingStep struct{}
+
+func (blockingStep) Plan(ctx context.Context, db *MongoClient) (string, error) { return "wait", nil }
+
+func (blockingStep) Apply(ctx context.Context, db *MongoClient) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestMigratorMock(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("dry run", func(mt *mtest.T) {
+		db := newMockRepo(mt).Database
+		m, err := NewMigrator(db, []Migration{backfillMigration(1), backfillMigration(2), backfillMigration(3)}, WithDryRun())
+		require.NoError(mt, err)
+		mt.AddMockResponses(
+			mockCursor(mt, bson.D{{Key: "_id", Value: 1}}),
+			mockCursor(mt, bson.D{{Key: "n", Value: 3}}),
+			mockCursor(mt),
+		)
+
+		report, err := m.Up(mt.Context(), LatestVersion)
+		require.NoError(mt, err)
+		assert.True(mt, report.DryRun)
+		assert.Equal(mt, 1, report.From)
+		assert.Equal(mt, 1, report.To, "a dry run applies nothing")
+		assert.Equal(mt, []MigrationResult{
+			{Version: 2, Description: "backfill 2", Changes: []string{"set age in 3 documents of Restaurant"}},
+			{Version: 3, Description: "backfill 3", Changes: []string{"set age in 0 documents of Restaurant"}},
+		}, report.Migrations)
+		assert.Equal(mt, []string{"find", "aggregate", "aggregate"}, sentCommands(mt), "no lock, update or record")
+	})
+
+	mt.Run("lock lost between migrations", func(mt *mtest.T) {
+		db := newMockRepo(mt).Database
+		m, err := NewMigrator(db, []Migration{backfillMigration(1), backfillMigration(2)})
+		require.NoError(mt, err)
+		mt.AddMockResponses(
+			mockOK,         // lock
+			mockCursor(mt), // applied
+			mockOK,         // renew before 1
+			mockCursor(mt), // plan 1
+			mockOK,         // apply 1
+			mockOK,         // record 1
+			mockUnmatched,  // renew before 2: another instance took over
+			mockOK,         // unlock
+		)
+
+		report, err := m.Up(mt.Context(), LatestVersion)
+		assert.ErrorIs(mt, err, ErrMigrationLocked)
+		require.Len(mt, report.Migrations, 1, "migration 2 is not started")
+		assert.Equal(mt, 1, report.To)
+
+		assert.Equal(mt, []string{"insert", "find", "update", "aggregate", "update", "insert", "update", "delete"}, sentCommands(mt))
+	})
+
+	mt.Run("lock lost during a migration", func(mt *mtest.T) {
+		db := newMockRepo(mt).Database
+		m, err := NewMigrator(db, []Migration{{Version: 1, Up: []MigrationStep{blockingStep{}}}}, WithLockTTL(30*time.Millisecond))
+		require.NoError(mt, err)
+		mt.AddMockResponses(
+			mockOK,         // lock
+			mockCursor(mt), // applied
+			mockOK,         // renew before 1
+			mockUnmatched,  // heartbeat: another instance took over
+			mockOK,         // unlock
+		)
+
+		report, err := m.Up(mt.Context(), LatestVersion)
+		assert.ErrorIs(mt, err, ErrMigrationLocked, "the heartbeat stops the running step")
+		assert.Equal(mt, 0, report.To)
+
+		var renewal bson.Raw
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			if e.CommandName == "update" {
+				renewal = e.Command
+			}
+		}
+		require.NotNil(mt, renewal)
+		filter := renewal.Lookup("updates").Array().Index(0).Value().Document().Lookup("q").Document()
+		assert.Equal(mt, bson.M{"_id": migrationLockID, "owner": m.owner}, normalize(mt.T, filter),
+			"renewals only match the lock of this migrator")
+	})
+}
@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var _ restaurant.RestaurantAnalyticsReader = (*RestaurantRepo)(nil)
+
+var fieldMenuItemPrice = bsonPath("Menu.Price")
+
+// Computed fields of the analytics pipelines. Like distanceField they are not part of
+// RestaurantBSON.
+const (
+	averageField = "_average"
+	countField   = "_count"
+	minField     = "_min"
+	maxField     = "_max"
+)
+
+type ratingSummaryBSON struct {
+	Average float64 `bson:"_average"`
+	Count   int     `bson:"_count"`
+}
+
+type ratedRestaurantBSON struct {
+	restaurant.RestaurantBSON `bson:",inline"`
+	Average                   float64 `bson:"_average"`
+	Count                     int     `bson:"_count"`
+}
+
+type priceStatsBSON struct {
+	Min     float64 `bson:"_min"`
+	Max     float64 `bson:"_max"`
+	Average float64 `bson:"_average"`
+	Count   int     `bson:"_count"`
+}
+
+// ref returns the aggregation expression of a field path.
+func ref(path string) string {
+	return "$" + path
+}
+
+// sizeOf counts the elements of an array field, treating a missing or null array as empty.
+func sizeOf(path string) bson.M {
+	return bson.M{"$size": bson.M{"$ifNull": bson.A{ref(path), bson.A{}}}}
+}
+
+// orZero replaces the null an accumulator expression yields on an empty array with 0.
+func orZero(expr bson.M) bson.M {
+	return bson.M{"$ifNull": bson.A{expr, 0}}
+}
+
+// AverageRating averages the rating scores in the database with $avg.
+func (r *RestaurantRepo) AverageRating(ctx context.Context, id string) (*restaurant.RatingSummary, error) {
+	var docs []ratingSummaryBSON
+	if err := r.aggregateOne(ctx, id, bson.M{
+		averageField: orZero(bson.M{"$avg": ref(fieldRatingScore)}),
+		countField:   sizeOf(fieldRatings),
+	}, &docs); err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("restaurant %s: %w", id, restaurant.ErrNotFound)
+	}
+	return &restaurant.RatingSummary{RestaurantID: id, Average: docs[0].Average, Count: docs[0].Count}, nil
+}
+
+// TopRatedByCity ranks the restaurants of city in the database and only fetches the
+// top limit documents.
+func (r *RestaurantRepo) TopRatedByCity(ctx context.Context, city string, limit int) ([]*restaurant.RatedRestaurant, error) {
+	if city == "" {
+		return nil, restaurant.ErrEmptyCriteria
+	}
+	match := addressFilter(restaurant.Address{City: city})
+	match[fieldRatings+".0"] = bson.M{"$exists": true}
+	pipeline := bson.A{
+		bson.M{"$match": match},
+		bson.M{"$addFields": bson.M{
+			averageField: bson.M{"$avg": ref(fieldRatingScore)},
+			countField:   bson.M{"$size": ref(fieldRatings)},
+		}},
+		bson.M{"$sort": bson.D{{Key: averageField, Value: -1}, {Key: countField, Value: -1}, {Key: fieldID, Value: 1}}},
+		bson.M{"$limit": restaurant.NormalizeLimit(limit)},
+	}
+	var docs []*ratedRestaurantBSON
+	if err := r.Database.Aggregate(ctx, RESTAURANT_COLLECTION, pipeline, &docs); err != nil {
+		return nil, err
+	}
+
+	results := make([]*restaurant.RatedRestaurant, len(docs))
+	for i, doc := range docs {
+		results[i] = &restaurant.RatedRestaurant{
+			Restaurant: doc.RestaurantFromBSONToDTO(),
+			Average:    doc.Average,
+			Count:      doc.Count,
+		}
+	}
+	return results, nil
+}
+
+// MenuPriceStats computes the menu price range and average in the database.
+func (r *RestaurantRepo) MenuPriceStats(ctx context.Context, id string) (*restaurant.PriceStats, error) {
+	prices := ref(fieldMenuItemPrice)
+	var docs []priceStatsBSON
+	if err := r.aggregateOne(ctx, id, bson.M{
+		minField:     orZero(bson.M{"$min": prices}),
+		maxField:     orZero(bson.M{"$max": prices}),
+		averageField: orZero(bson.M{"$avg": prices}),
+		countField:   sizeOf(fieldMenu),
+	}, &docs); err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("restaurant %s: %w", id, restaurant.ErrNotFound)
+	}
+	s := docs[0]
+	return &restaurant.PriceStats{RestaurantID: id, Min: s.Min, Max: s.Max, Average: s.Average, Count: s.Count}, nil
+}
+
+// aggregateOne projects the restaurant id with projection into docs, which stays empty
+// when there is no such restaurant.
+func (r *RestaurantRepo) aggregateOne(ctx context.Context, id string, projection bson.M, docs any) error {
+	pipeline := bson.A{
+		bson.M{"$match": idFilter(id)},
+		bson.M{"$project": projection},
+	}
+	return r.Database.Aggregate(ctx, RESTAURANT_COLLECTION, pipeline, docs)
+}
@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	restaurant "github.com/testingrepo/domain"
+)
+
+// Document paths used by the filters and updates, derived from the bson tags of
+// RestaurantBSON so they cannot drift from the stored layout.
+var (
+	fieldID           = bsonPath("ID")
+	fieldName         = bsonPath("Name")
+	fieldAge          = bsonPath("Age")
+	fieldStreet       = bsonPath("Address.Street")
+	fieldCity         = bsonPath("Address.City")
+	fieldState        = bsonPath("Address.State")
+	fieldZip          = bsonPath("Address.Zip")
+	fieldOwners       = bsonPath("Owners")
+	fieldEmployees    = bsonPath("Employees")
+	fieldEmployeeName = bsonPath("Employees.Name")
+	fieldMenu         = bsonPath("Menu")
+	fieldMenuItemName = bsonPath("Menu.Name")
+	fieldRatings      = bsonPath("Ratings")
+	fieldRatingScore  = bsonPath("Ratings.Score")
+)
+
+// bsonPath maps a dotted RestaurantBSON field path such as "Menu.Name" to its document
+// path, "menu.name". It panics on unknown fields, so a renamed field fails at package
+// initialisation rather than silently matching nothing.
+func bsonPath(goPath string) string {
+	t := reflect.TypeFor[restaurant.RestaurantBSON]()
+	var parts []string
+	for _, name := range strings.Split(goPath, ".") {
+		for t.Kind() == reflect.Slice || t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		f, ok := t.FieldByName(name)
+		if t.Kind() != reflect.Struct || !ok {
+			panic(fmt.Sprintf("mongo: RestaurantBSON has no field %s", goPath))
+		}
+		parts = append(parts, bsonName(f))
+		t = f.Type
+	}
+	return strings.Join(parts, ".")
+}
+
+// bsonName returns the key the driver stores f under: the tag name, or the lowercased
+// field name when the tag does not set one.
+func bsonName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("bson"), ",")
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
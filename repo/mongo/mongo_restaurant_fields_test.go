@@ -0,0 +1,156 @@
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	restaurant "github.com/testingrepo/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestBSONPath(t *testing.T) {
+	assert.Equal(t, "_id", bsonPath("ID"))
+	assert.Equal(t, "owners", bsonPath("Owners"))
+	assert.Equal(t, "ratings.score", bsonPath("Ratings.Score"))
+	assert.Equal(t, "menu.name", bsonPath("Menu.Name"))
+	assert.Equal(t, "address.zip", bsonPath("Address.Zip"))
+	assert.Panics(t, func() { bsonPath("Rating") })
+	assert.Panics(t, func() { bsonPath("Name.First") })
+}
+
+func TestRestaurantFilters(t *testing.T) {
+	assert.Equal(t, bson.M{"owners": "Ann"}, ownerFilter("Ann"))
+	assert.Equal(t, bson.M{"ratings.score": 5}, ratingFilter(5))
+	assert.Equal(t, bson.M{"menu.name": "Pho"}, menuItemFilter("Pho"))
+	assert.Equal(t, bson.M{
+		"address.city": primitive.Regex{Pattern: `^St\. Louis$`, Options: "i"},
+		"address.zip":  primitive.Regex{Pattern: "^631"},
+	}, addressFilter(restaurant.Address{City: "St. Louis", Zip: "631"}))
+}
+
+// TestRestaurantRepoWritesOnNullArrays covers documents whose arrays were stored as
+// null from nil slices, which $push would reject.
+func TestRestaurantRepoWritesOnNullArrays(t *testing.T) {
+	repo := newTestRepo(t, newTestClient(t))
+	ctx := context.Background()
+	require.NoError(t, repo.InsertRestaurant(ctx, &restaurant.Restaurant{ID: "r1", Name: "Bare"}))
+
+	note := restaurant.Rating{Score: 4, User: "$where", Note: "$ratings"}
+	require.NoError(t, repo.AddRating(ctx, "r1", note))
+	require.NoError(t, repo.UpdateEmployee(ctx, "r1", domainEmployee("Ann", "Chef")))
+	require.NoError(t, repo.UpdateEmployee(ctx, "r1", domainEmployee("Ann", "Owner")))
+	require.NoError(t, repo.UpdateMenu(ctx, "r1", []restaurant.MenuItem{{Name: "Pho", Price: 14}}))
+
+	found, err := repo.FindByRating(ctx, 4)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, []restaurant.Rating{note}, found[0].Ratings)
+	assert.Equal(t, []restaurant.Employee{domainEmployee("Ann", "Owner")}, found[0].Employees)
+	byItem, err := repo.FindByMenuItem(ctx, "Pho")
+	require.NoError(t, err)
+	assert.Len(t, byItem, 1)
+
+	assert.ErrorIs(t, repo.UpdateMenu(ctx, "missing", nil), restaurant.ErrNotFound)
+}
+
+func domainEmployee(name, role string) restaurant.Employee {
+	return restaurant.Employee{Name: name, Role: role, Age: 30}
+}
+
+// updateOf returns the filter and update of the single statement of a recorded update
+// command.
+func updateOf(t *testing.T, cmd bson.Raw) (filter bson.M, update string) {
+	t.Helper()
+	stmt := cmd.Lookup("updates").Array().Index(0).Value().Document()
+	return normalize(t, stmt.Lookup("q").Document()), stmt.Lookup("u").String()
+}
+
+// extJSON renders an update like bson.RawValue.String renders the recorded one. The
+// updates built by the repository have one key per document, so the order is stable.
+func extJSON(t *testing.T, update any) string {
+	t.Helper()
+	typ, data, err := bson.MarshalValue(update)
+	require.NoError(t, err)
+	return bson.RawValue{Type: typ, Value: data}.String()
+}
+
+// TestRestaurantRepoWriteCommands checks the updates sent for documents whose arrays
+// may be null and how MatchedCount maps to ErrNotFound, against the mock deployment.
+func TestRestaurantRepoWriteCommands(t *testing.T) {
+	matchedOne := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}, {Key: "nModified", Value: 1}}
+	matchedNone := bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}, {Key: "nModified", Value: 0}}
+	appendTo := func(field string, doc any) bson.A {
+		return bson.A{bson.M{"$set": bson.M{field: bson.M{"$concatArrays": bson.A{
+			bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+			bson.M{"$literal": bson.A{doc}},
+		}}}}}
+	}
+	ann := domainEmployee("Ann", "Chef")
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateMenu sets the whole menu", func(mt *mtest.T) {
+		mt.AddMockResponses(matchedOne)
+		menu := []restaurant.MenuItem{{Name: "Pho", Price: 14}}
+		require.NoError(mt, newMockRepo(mt).UpdateMenu(mt.Context(), "r1", menu))
+
+		filter, update := updateOf(mt.T, mt.GetStartedEvent().Command)
+		assert.Equal(mt, bson.M{"_id": "r1"}, filter)
+		assert.Equal(mt, extJSON(mt.T, bson.M{"$set": bson.M{"menu": restaurant.ConvertMenuItemsToBSON(menu)}}), update)
+	})
+
+	mt.Run("AddRating appends with a pipeline", func(mt *mtest.T) {
+		mt.AddMockResponses(matchedOne)
+		note := restaurant.Rating{Score: 4, User: "$where", Note: "$ratings"}
+		require.NoError(mt, newMockRepo(mt).AddRating(mt.Context(), "r1", note))
+
+		filter, update := updateOf(mt.T, mt.GetStartedEvent().Command)
+		assert.Equal(mt, bson.M{"_id": "r1"}, filter)
+		assert.Equal(mt, extJSON(mt.T, appendTo("ratings", restaurant.RatingBSON(note))), update,
+			"null ratings become [] and $-prefixed values are literals")
+	})
+
+	mt.Run("UpdateEmployee replaces a matching employee", func(mt *mtest.T) {
+		mt.AddMockResponses(matchedOne)
+		require.NoError(mt, newMockRepo(mt).UpdateEmployee(mt.Context(), "r1", ann))
+
+		filter, update := updateOf(mt.T, mt.GetStartedEvent().Command)
+		assert.Equal(mt, bson.M{"_id": "r1", "employees.name": "Ann"}, filter)
+		assert.Equal(mt, extJSON(mt.T, bson.M{"$set": bson.M{"employees.$": restaurant.EmployeeBSON(ann)}}), update)
+		assert.Nil(mt, mt.GetStartedEvent(), "nothing else is sent once the replace matched")
+	})
+
+	mt.Run("UpdateEmployee appends a new employee", func(mt *mtest.T) {
+		mt.AddMockResponses(matchedNone, matchedOne)
+		require.NoError(mt, newMockRepo(mt).UpdateEmployee(mt.Context(), "r1", ann))
+
+		mt.GetStartedEvent()
+		filter, update := updateOf(mt.T, mt.GetStartedEvent().Command)
+		assert.Equal(mt, bson.M{"_id": "r1", "employees.name": bson.M{"$ne": "Ann"}}, filter)
+		assert.Equal(mt, extJSON(mt.T, appendTo("employees", restaurant.EmployeeBSON(ann))), update)
+		assert.Nil(mt, mt.GetStartedEvent())
+	})
+
+	mt.Run("UpdateEmployee retries the replace when the append matched nothing", func(mt *mtest.T) {
+		mt.AddMockResponses(matchedNone, matchedNone, matchedOne)
+		require.NoError(mt, newMockRepo(mt).UpdateEmployee(mt.Context(), "r1", ann),
+			"a concurrent call added the employee between the replace and the append")
+
+		mt.GetStartedEvent()
+		mt.GetStartedEvent()
+		filter, _ := updateOf(mt.T, mt.GetStartedEvent().Command)
+		assert.Equal(mt, bson.M{"_id": "r1", "employees.name": "Ann"}, filter)
+	})
+
+	mt.Run("unmatched writes fail with ErrNotFound", func(mt *mtest.T) {
+		repo := newMockRepo(mt)
+		mt.AddMockResponses(matchedNone, matchedNone, matchedNone, matchedNone, matchedNone)
+		assert.ErrorIs(mt, repo.UpdateMenu(mt.Context(), "missing", nil), restaurant.ErrNotFound)
+		assert.ErrorIs(mt, repo.AddRating(mt.Context(), "missing", restaurant.Rating{Score: 1}), restaurant.ErrNotFound)
+		assert.ErrorIs(mt, repo.UpdateEmployee(mt.Context(), "missing", ann), restaurant.ErrNotFound)
+	})
+}
@@ -0,0 +1,60 @@
+package mongo
+
+import (
+	"context"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ restaurant.RestaurantGeoReader = (*RestaurantRepo)(nil)
+
+var fieldLocation = bsonPath("Address.Location")
+
+// distanceField holds the $geoNear distance. It is not part of RestaurantBSON, so it
+// cannot clash with a stored field.
+const distanceField = "_distance"
+
+type restaurantDistanceBSON struct {
+	restaurant.RestaurantBSON `bson:",inline"`
+	Distance                  float64 `bson:"_distance"`
+}
+
+// GeoIndex is the 2dsphere index FindNear requires.
+func GeoIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys:    bson.D{{Key: fieldLocation, Value: "2dsphere"}},
+		Options: options.Index().SetName("address_location_2dsphere"),
+	}
+}
+
+// FindNear returns the restaurants within radiusMeters of point, nearest first, using
+// $geoNear. It needs GeoIndex, created by EnsureIndexes.
+func (r *RestaurantRepo) FindNear(ctx context.Context, point restaurant.GeoPoint, radiusMeters float64) ([]*restaurant.RestaurantDistance, error) {
+	if err := restaurant.ValidateNear(point, radiusMeters); err != nil {
+		return nil, err
+	}
+	pipeline := bson.A{bson.M{"$geoNear": bson.M{
+		"near":          restaurant.GeoJSONPointFrom(point),
+		"key":           fieldLocation,
+		"distanceField": distanceField,
+		"maxDistance":   radiusMeters,
+		"spherical":     true,
+	}}}
+	var docs []*restaurantDistanceBSON
+	if err := r.Database.Aggregate(ctx, RESTAURANT_COLLECTION, pipeline, &docs); err != nil {
+		return nil, err
+	}
+
+	results := make([]*restaurant.RestaurantDistance, len(docs))
+	for i, doc := range docs {
+		results[i] = &restaurant.RestaurantDistance{
+			Restaurant:     doc.RestaurantFromBSONToDTO(),
+			DistanceMeters: doc.Distance,
+		}
+	}
+	return results, nil
+}
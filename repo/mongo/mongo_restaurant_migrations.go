@@ -0,0 +1,54 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// RestaurantMigrations evolves RESTAURANT_COLLECTION. Append new migrations with the
+// next version; never change or renumber applied ones.
+func RestaurantMigrations() []Migration {
+	geo, text := GeoIndex(), TextIndex()
+	return []Migration{
+		{
+			Version:     1,
+			Description: "2dsphere index for FindNear",
+			Up:          []MigrationStep{CreateIndex(RESTAURANT_COLLECTION, geo)},
+			Down:        []MigrationStep{DropIndex(RESTAURANT_COLLECTION, *geo.Options.Name)},
+		},
+		{
+			Version:     2,
+			Description: "text index for SearchText",
+			Up:          []MigrationStep{CreateIndex(RESTAURANT_COLLECTION, text)},
+			Down:        []MigrationStep{DropIndex(RESTAURANT_COLLECTION, *text.Options.Name)},
+		},
+		{
+			// Documents written before the converters normalised nil slices store null
+			// arrays, which $push and array filters reject.
+			Version:     3,
+			Description: "replace missing and null arrays with empty arrays",
+			Up: []MigrationStep{
+				emptyArrayBackfill(fieldOwners),
+				emptyArrayBackfill(fieldEmployees),
+				emptyArrayBackfill(fieldMenu),
+				emptyArrayBackfill(fieldRatings),
+			},
+		},
+	}
+}
+
+func emptyArrayBackfill(field string) MigrationStep {
+	return Backfill(RESTAURANT_COLLECTION, "set "+field+" to []",
+		bson.M{field: nil},
+		bson.M{"$set": bson.M{field: bson.A{}}})
+}
+
+// MigrateRestaurants applies every pending RestaurantMigrations migration.
+func (r *RestaurantRepo) MigrateRestaurants(ctx context.Context, opts ...MigratorOption) (*MigrationReport, error) {
+	m, err := NewMigrator(r.Database, RestaurantMigrations(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	return m.Up(ctx, LatestVersion)
+}
@@ -0,0 +1,102 @@
+package mongo
+
+import (
+	"context"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// sortFields maps the domain sort fields to document fields.
+var sortFields = map[restaurant.SortField]string{
+	restaurant.SortByID:   fieldID,
+	restaurant.SortByName: fieldName,
+	restaurant.SortByAge:  fieldAge,
+}
+
+func (r *RestaurantRepo) FindByNamePaged(ctx context.Context, name string, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, nameFilter(name), page)
+}
+
+func (r *RestaurantRepo) FindByOwnerPaged(ctx context.Context, owner string, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, ownerFilter(owner), page)
+}
+
+func (r *RestaurantRepo) FindByRatingPaged(ctx context.Context, score int, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, ratingFilter(score), page)
+}
+
+func (r *RestaurantRepo) FindByMenuItemPaged(ctx context.Context, item string, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	return r.findPage(ctx, menuItemFilter(item), page)
+}
+
+// findPage runs filter with keyset pagination: the cursor restricts the query to
+// documents sorted after the last one returned, so deep pages stay cheap.
+func (r *RestaurantRepo) findPage(ctx context.Context, filter bson.M, page restaurant.PageRequest) (*restaurant.RestaurantPage, error) {
+	page = page.Normalize()
+	field, ok := sortFields[page.SortBy]
+	if !ok {
+		return nil, restaurant.ErrInvalidCursor
+	}
+
+	total, err := r.Database.CountDocuments(ctx, RESTAURANT_COLLECTION, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	query := filter
+	if page.Cursor != "" {
+		cursor, err := restaurant.DecodeCursor(page)
+		if err != nil {
+			return nil, err
+		}
+		query = bson.M{"$and": bson.A{filter, keysetFilter(field, page.Descending, cursor)}}
+	}
+
+	dir := 1
+	if page.Descending {
+		dir = -1
+	}
+	sort := bson.D{{Key: field, Value: dir}}
+	if field != fieldID {
+		sort = append(sort, bson.E{Key: fieldID, Value: dir})
+	}
+	opts := options.Find().SetSort(sort).SetLimit(int64(page.Limit + 1))
+
+	var docs []*restaurant.RestaurantBSON
+	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, query, &docs, opts); err != nil {
+		return nil, err
+	}
+
+	result := &restaurant.RestaurantPage{TotalCount: total}
+	if len(docs) > page.Limit {
+		docs = docs[:page.Limit]
+		result.HasMore = true
+	}
+	result.Items = make([]*restaurant.Restaurant, len(docs))
+	for i, doc := range docs {
+		result.Items[i] = doc.RestaurantFromBSONToDTO()
+	}
+	if result.HasMore {
+		result.NextCursor = restaurant.EncodeCursor(page, result.Items[len(result.Items)-1])
+	}
+	return result, nil
+}
+
+// keysetFilter matches documents ordered after the cursor position.
+func keysetFilter(field string, descending bool, cursor restaurant.PageCursor) bson.M {
+	op := "$gt"
+	if descending {
+		op = "$lt"
+	}
+	if field == fieldID {
+		return bson.M{fieldID: bson.M{op: cursor.ID}}
+	}
+	value := cursor.Value()
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, fieldID: bson.M{op: cursor.ID}},
+	}}
+}
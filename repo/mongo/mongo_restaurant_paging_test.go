@@ -0,0 +1,96 @@
+package mongo
+
+import (
+	"testing"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// sortOf returns the sort document of a recorded find, keeping the key order.
+func sortOf(t *testing.T, find bson.Raw) bson.D {
+	t.Helper()
+	var sort bson.D
+	require.NoError(t, find.Lookup("sort").Unmarshal(&sort))
+	return sort
+}
+
+func TestRestaurantRepoFindPagedKeyset(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	mt.Run("name cursor", func(mt *mtest.T) {
+		repo := newMockRepo(mt)
+		ns := mt.DB.Name() + "." + RESTAURANT_COLLECTION
+		doc := func(id, name string) bson.D {
+			return bson.D{{Key: fieldID, Value: id}, {Key: fieldName, Value: name}}
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 5}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, doc("r3", "Bistro"), doc("r1", "Cafe"), doc("r4", "Diner")),
+		)
+
+		page := restaurant.PageRequest{Limit: 2, SortBy: restaurant.SortByName}
+		page.Cursor = restaurant.EncodeCursor(page, &restaurant.Restaurant{ID: "r2", Name: "Bistro"})
+		result, err := repo.FindByOwnerPaged(mt.Context(), "alice", page)
+		require.NoError(mt, err)
+
+		assert.Equal(mt, int64(5), result.TotalCount)
+		assert.True(mt, result.HasMore, "the extra document shows another page exists")
+		require.Len(mt, result.Items, 2)
+		assert.Equal(mt, []string{"r3", "r1"}, []string{result.Items[0].ID, result.Items[1].ID})
+		next, err := restaurant.DecodeCursor(restaurant.PageRequest{Cursor: result.NextCursor, SortBy: restaurant.SortByName})
+		require.NoError(mt, err)
+		assert.Equal(mt, restaurant.PageCursor{SortBy: restaurant.SortByName, ID: "r1", Name: "Cafe"}, next)
+
+		count := mt.GetStartedEvent()
+		require.Equal(mt, "aggregate", count.CommandName)
+		find := mt.GetStartedEvent()
+		require.Equal(mt, "find", find.CommandName)
+
+		want := bson.M{"$and": bson.A{
+			ownerFilter("alice"),
+			bson.M{"$or": bson.A{
+				bson.M{fieldName: bson.M{"$gt": "Bistro"}},
+				bson.M{fieldName: "Bistro", fieldID: bson.M{"$gt": "r2"}},
+			}},
+		}}
+		assert.Equal(mt, normalize(mt.T, want), normalize(mt.T, find.Command.Lookup("filter").Document()),
+			"the cursor restricts the query to documents after it")
+		assert.Equal(mt, bson.D{{Key: fieldName, Value: int32(1)}, {Key: fieldID, Value: int32(1)}}, sortOf(mt.T, find.Command),
+			"ties on the sort field are ordered by ID")
+		assert.EqualValues(mt, 3, find.Command.Lookup("limit").AsInt64(), "one extra document is fetched to detect the next page")
+	})
+
+	mt.Run("descending id without cursor", func(mt *mtest.T) {
+		repo := newMockRepo(mt)
+		ns := mt.DB.Name() + "." + RESTAURANT_COLLECTION
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: fieldID, Value: "r1"}}),
+		)
+
+		result, err := repo.FindByNamePaged(mt.Context(), "Cafe", restaurant.PageRequest{Descending: true})
+		require.NoError(mt, err)
+		assert.False(mt, result.HasMore)
+		assert.Empty(mt, result.NextCursor)
+
+		mt.GetStartedEvent()
+		find := mt.GetStartedEvent()
+		assert.Equal(mt, normalize(mt.T, nameFilter("Cafe")), normalize(mt.T, find.Command.Lookup("filter").Document()))
+		assert.Equal(mt, bson.D{{Key: fieldID, Value: int32(-1)}}, sortOf(mt.T, find.Command))
+	})
+
+	mt.Run("cursor of another ordering", func(mt *mtest.T) {
+		repo := newMockRepo(mt)
+		ns := mt.DB.Name() + "." + RESTAURANT_COLLECTION
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}))
+
+		page := restaurant.PageRequest{SortBy: restaurant.SortByAge}
+		page.Cursor = restaurant.EncodeCursor(restaurant.PageRequest{SortBy: restaurant.SortByName}, &restaurant.Restaurant{ID: "r1"})
+		_, err := repo.FindByNamePaged(mt.Context(), "Cafe", page)
+		assert.ErrorIs(mt, err, restaurant.ErrInvalidCursor)
+	})
+}
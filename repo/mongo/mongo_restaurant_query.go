@@ -0,0 +1,123 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// queryFields maps the domain query fields to document paths. Query fields are named
+// after the RestaurantBSON field paths, so the paths come from the bson tags.
+var queryFields = func() map[restaurant.QueryField]string {
+	fields := []restaurant.QueryField{
+		restaurant.FieldID, restaurant.FieldName, restaurant.FieldEmail, restaurant.FieldAge,
+		restaurant.FieldStreet, restaurant.FieldCity, restaurant.FieldState, restaurant.FieldZip,
+		restaurant.FieldOwners,
+		restaurant.FieldEmployees, restaurant.FieldEmployeeName, restaurant.FieldEmployeeRole, restaurant.FieldEmployeeAge,
+		restaurant.FieldMenu, restaurant.FieldMenuItemName, restaurant.FieldMenuItemDescription, restaurant.FieldMenuItemPrice,
+		restaurant.FieldRatings, restaurant.FieldRatingScore, restaurant.FieldRatingUser, restaurant.FieldRatingNote,
+	}
+	paths := make(map[restaurant.QueryField]string, len(fields))
+	for _, f := range fields {
+		paths[f] = bsonPath(string(f))
+	}
+	return paths
+}()
+
+func (r *RestaurantRepo) Find(ctx context.Context, q restaurant.RestaurantQuery) ([]*restaurant.Restaurant, error) {
+	filter, err := QueryToBSON(q)
+	if err != nil {
+		return nil, err
+	}
+	var docs []*restaurant.RestaurantBSON
+	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, filter, &docs); err != nil {
+		return nil, err
+	}
+
+	var restaurants = make([]*restaurant.Restaurant, len(docs))
+	for i, restaurant := range docs {
+		restaurants[i] = restaurant.RestaurantFromBSONToDTO()
+	}
+
+	return restaurants, nil
+}
+
+// QueryToBSON translates a RestaurantQuery into a Mongo filter document.
+func QueryToBSON(q restaurant.RestaurantQuery) (bson.M, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	return translate(q, "")
+}
+
+// translate builds the filter for q. Inside $elemMatch, prefix is the array path that
+// is stripped from the children's fields.
+func translate(q restaurant.RestaurantQuery, prefix string) (bson.M, error) {
+	switch q.Op {
+	case restaurant.OpAnd, restaurant.OpOr:
+		children, err := translateAll(q.Children, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$" + string(q.Op): children}, nil
+	case restaurant.OpNot:
+		children, err := translateAll(q.Children, prefix)
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{"$nor": children}, nil
+	case restaurant.OpElemMatch:
+		path, err := fieldPath(q.Field, prefix)
+		if err != nil {
+			return nil, err
+		}
+		children, err := translateAll(q.Children, queryFields[q.Field])
+		if err != nil {
+			return nil, err
+		}
+		return bson.M{path: bson.M{"$elemMatch": bson.M{"$and": children}}}, nil
+	}
+
+	path, err := fieldPath(q.Field, prefix)
+	if err != nil {
+		return nil, err
+	}
+	switch q.Op {
+	case restaurant.OpEq:
+		return bson.M{path: q.Value}, nil
+	case restaurant.OpIn:
+		return bson.M{path: bson.M{"$in": q.Values}}, nil
+	case restaurant.OpContains:
+		return bson.M{path: bson.M{"$regex": regexp.QuoteMeta(q.Value.(string)), "$options": "i"}}, nil
+	}
+	return bson.M{path: bson.M{"$" + string(q.Op): q.Value}}, nil
+}
+
+func translateAll(queries []restaurant.RestaurantQuery, prefix string) (bson.A, error) {
+	out := make(bson.A, len(queries))
+	for i, c := range queries {
+		m, err := translate(c, prefix)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = m
+	}
+	return out, nil
+}
+
+// fieldPath returns the document path of field, relative to prefix inside $elemMatch.
+func fieldPath(field restaurant.QueryField, prefix string) (string, error) {
+	path, ok := queryFields[field]
+	if !ok {
+		return "", fmt.Errorf("%w: unknown field %q", restaurant.ErrInvalidQuery, field)
+	}
+	if prefix == "" {
+		return path, nil
+	}
+	return strings.TrimPrefix(path, prefix+"."), nil
+}
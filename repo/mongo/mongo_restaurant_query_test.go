@@ -0,0 +1,35 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestQueryToBSON(t *testing.T) {
+	q := restaurant.And(
+		restaurant.ElemMatch(restaurant.FieldMenu,
+			restaurant.Eq(restaurant.FieldMenuItemName, "Pho"),
+			restaurant.Lt(restaurant.FieldMenuItemPrice, 10),
+		),
+		restaurant.Eq(restaurant.FieldCity, "Austin"),
+		restaurant.Not(restaurant.Contains(restaurant.FieldName, "a.b")),
+	)
+
+	filter, err := QueryToBSON(q)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{"$and": bson.A{
+		bson.M{"menu": bson.M{"$elemMatch": bson.M{"$and": bson.A{
+			bson.M{"name": "Pho"},
+			bson.M{"price": bson.M{"$lt": 10}},
+		}}}},
+		bson.M{"address.city": "Austin"},
+		bson.M{"$nor": bson.A{bson.M{"name": bson.M{"$regex": `a\.b`, "$options": "i"}}}},
+	}}, filter)
+
+	_, err = QueryToBSON(restaurant.Eq("Unknown", 1))
+	assert.ErrorIs(t, err, restaurant.ErrInvalidQuery)
+}
@@ -2,11 +2,14 @@ package mongo
 
 import (
 	"context"
-	"time"
+	"fmt"
+	"regexp"
 
 	restaurant "github.com/testingrepo/domain"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // Collections
@@ -14,23 +17,55 @@ var (
 	RESTAURANT_COLLECTION = "Restaurant"
 )
 
+var (
+	_ restaurant.RestaurantRepository  = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantPagedReader = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantStreamer    = (*RestaurantRepo)(nil)
+	_ restaurant.RestaurantQuerier     = (*RestaurantRepo)(nil)
+)
+
 type RestaurantRepo struct {
 	Database *MongoClient
 }
 
-func NewRestaurantRepo() *RestaurantRepo {
-	mongo, err := ConnectMongo(MongoConfig{"mongosrv://localhost:8080", "Example", 30 * time.Second})
+// NewRestaurantRepo connects to the server described by cfg. The repository owns the
+// connection and must be released with Close.
+func NewRestaurantRepo(cfg MongoConfig) (*RestaurantRepo, error) {
+	mongo, err := ConnectMongo(cfg)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	return &RestaurantRepo{
 		Database: mongo,
-	}
+	}, nil
+}
+
+// NewRestaurantRepoFromClient returns a repository on an existing connection, e.g. one
+// shared with other repositories.
+func NewRestaurantRepoFromClient(client *MongoClient) *RestaurantRepo {
+	return &RestaurantRepo{Database: client}
+}
+
+// Close waits for in-flight operations and disconnects; see MongoClient.Close.
+func (r *RestaurantRepo) Close(ctx context.Context) error {
+	return r.Database.Close(ctx)
+}
+
+// RestaurantIndexes are the indexes the optional RestaurantRepo capabilities need.
+func RestaurantIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{GeoIndex(), TextIndex()}
+}
+
+// EnsureIndexes creates the RestaurantIndexes that do not exist yet. Deployments
+// managed with MigrateRestaurants get them from the migrations instead.
+func (r *RestaurantRepo) EnsureIndexes(ctx context.Context) error {
+	_, err := r.Database.CreateIndexes(ctx, RESTAURANT_COLLECTION, RestaurantIndexes())
+	return err
 }
 
 func (r *RestaurantRepo) FindByName(ctx context.Context, name string) ([]*restaurant.Restaurant, error) {
-	filter := bson.M{"name": name}
+	filter := nameFilter(name)
 	var docs []*restaurant.RestaurantBSON
 	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, filter, &docs); err != nil {
 		return nil, err
@@ -45,7 +80,10 @@ func (r *RestaurantRepo) FindByName(ctx context.Context, name string) ([]*restau
 }
 
 func (r *RestaurantRepo) FindByAddress(ctx context.Context, addr restaurant.Address) ([]*restaurant.Restaurant, error) {
-	filter := bson.M{"address": addr}
+	if addr.IsZero() {
+		return nil, restaurant.ErrEmptyCriteria
+	}
+	filter := addressFilter(addr)
 	var docs []*restaurant.RestaurantBSON
 	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, filter, &docs); err != nil {
 		return nil, err
@@ -60,7 +98,7 @@ func (r *RestaurantRepo) FindByAddress(ctx context.Context, addr restaurant.Addr
 }
 
 func (r *RestaurantRepo) FindByOwner(ctx context.Context, owner string) ([]*restaurant.Restaurant, error) {
-	filter := bson.M{"owner": owner}
+	filter := ownerFilter(owner)
 	var docs []*restaurant.RestaurantBSON
 	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, filter, &docs); err != nil {
 		return nil, err
@@ -75,7 +113,7 @@ func (r *RestaurantRepo) FindByOwner(ctx context.Context, owner string) ([]*rest
 }
 
 func (r *RestaurantRepo) FindByRating(ctx context.Context, score int) ([]*restaurant.Restaurant, error) {
-	filter := bson.M{"rating": score}
+	filter := ratingFilter(score)
 	var docs []*restaurant.RestaurantBSON
 	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, filter, &docs); err != nil {
 		return nil, err
@@ -90,7 +128,7 @@ func (r *RestaurantRepo) FindByRating(ctx context.Context, score int) ([]*restau
 }
 
 func (r *RestaurantRepo) FindByMenuItem(ctx context.Context, item string) ([]*restaurant.Restaurant, error) {
-	filter := bson.M{"menu": item}
+	filter := menuItemFilter(item)
 	var docs []*restaurant.RestaurantBSON
 	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, filter, &docs); err != nil {
 		return nil, err
@@ -104,7 +142,47 @@ func (r *RestaurantRepo) FindByMenuItem(ctx context.Context, item string) ([]*re
 	return restaurants, nil
 }
 
+func nameFilter(name string) bson.M {
+	return bson.M{fieldName: name}
+}
+
+// addressFilter matches the set fields of addr as restaurant.Address.Matches does.
+// The case-insensitive regexes cannot use an index efficiently; the zip prefix can.
+func addressFilter(addr restaurant.Address) bson.M {
+	filter := bson.M{}
+	for path, value := range map[string]string{fieldStreet: addr.Street, fieldCity: addr.City, fieldState: addr.State} {
+		if value != "" {
+			filter[path] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(value) + "$", Options: "i"}
+		}
+	}
+	if addr.Zip != "" {
+		filter[fieldZip] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(addr.Zip)}
+	}
+	return filter
+}
+
+func ownerFilter(owner string) bson.M {
+	return bson.M{fieldOwners: owner}
+}
+
+func ratingFilter(score int) bson.M {
+	return bson.M{fieldRatingScore: score}
+}
+
+func menuItemFilter(item string) bson.M {
+	return bson.M{fieldMenuItemName: item}
+}
+
+func idFilter(id string) bson.M {
+	return bson.M{fieldID: id}
+}
+
+// InsertRestaurant stores rest, assigning it a new ObjectID hex string when ID is empty.
+// IDs are stored as strings so the writer methods can filter on the ID they were given.
 func (r *RestaurantRepo) InsertRestaurant(ctx context.Context, rest *restaurant.Restaurant) error {
+	if rest.ID == "" {
+		rest.ID = primitive.NewObjectID().Hex()
+	}
 	restBSON := restaurant.RestaurantFromDTOToBSON(*rest)
 	_, err := r.Database.InsertOne(ctx, RESTAURANT_COLLECTION, restBSON)
 	if err != nil {
@@ -113,32 +191,64 @@ func (r *RestaurantRepo) InsertRestaurant(ctx context.Context, rest *restaurant.
 	return nil
 }
 
+// UpdateMenu replaces the whole menu of restaurant id.
 func (r *RestaurantRepo) UpdateMenu(ctx context.Context, id string, menu []restaurant.MenuItem) error {
-	filter := bson.M{"_id": id}
-	update := restaurant.ConvertMenuItemsToBSON(menu)
-	_, err := r.Database.UpdateOne(ctx, RESTAURANT_COLLECTION, filter, update)
+	update := bson.M{"$set": bson.M{fieldMenu: restaurant.ConvertMenuItemsToBSON(menu)}}
+	res, err := r.Database.UpdateOne(ctx, RESTAURANT_COLLECTION, idFilter(id), update)
 	if err != nil {
 		return err
 	}
-	return nil
+	return matched(res, id)
 }
 
+// AddRating appends rating to the ratings of restaurant id.
 func (r *RestaurantRepo) AddRating(ctx context.Context, id string, rating restaurant.Rating) error {
-	filter := bson.M{"_id": id}
-	update := bson.M{"$push": bson.M{"rating": rating}}
-	_, err := r.Database.UpdateOne(ctx, RESTAURANT_COLLECTION, filter, update)
+	doc := restaurant.RatingBSON(rating)
+	res, err := r.Database.UpdateOne(ctx, RESTAURANT_COLLECTION, idFilter(id), appendUpdate(fieldRatings, doc))
 	if err != nil {
 		return err
 	}
-	return nil
+	return matched(res, id)
 }
 
+// UpdateEmployee replaces the employee of restaurant id with the same name, or adds
+// emp when there is none.
 func (r *RestaurantRepo) UpdateEmployee(ctx context.Context, id string, emp restaurant.Employee) error {
-	filter := bson.M{"_id": id}
-	update := bson.M{"$set": bson.M{"employee": emp}}
-	_, err := r.Database.UpdateOne(ctx, RESTAURANT_COLLECTION, filter, update)
-	if err != nil {
+	doc := restaurant.EmployeeBSON(emp)
+	replace := func() (*mongo.UpdateResult, error) {
+		filter := bson.M{fieldID: id, fieldEmployeeName: emp.Name}
+		return r.Database.UpdateOne(ctx, RESTAURANT_COLLECTION, filter, bson.M{"$set": bson.M{fieldEmployees + ".$": doc}})
+	}
+
+	res, err := replace()
+	if err != nil || res.MatchedCount > 0 {
+		return err
+	}
+	filter := bson.M{fieldID: id, fieldEmployeeName: bson.M{"$ne": emp.Name}}
+	if res, err = r.Database.UpdateOne(ctx, RESTAURANT_COLLECTION, filter, appendUpdate(fieldEmployees, doc)); err != nil || res.MatchedCount > 0 {
+		return err
+	}
+	// Neither matched: the restaurant is missing, or a concurrent call added the
+	// employee in between.
+	if res, err = replace(); err != nil {
 		return err
 	}
+	return matched(res, id)
+}
+
+// appendUpdate is an update pipeline appending doc to the array at field. Unlike
+// $push it also works when the array is null, as stored for nil slices (MongoDB 4.2+).
+// $literal keeps strings starting with "$" from being read as field paths.
+func appendUpdate(field string, doc any) bson.A {
+	return bson.A{bson.M{"$set": bson.M{field: bson.M{"$concatArrays": bson.A{
+		bson.M{"$ifNull": bson.A{"$" + field, bson.A{}}},
+		bson.M{"$literal": bson.A{doc}},
+	}}}}}
+}
+
+func matched(res *mongo.UpdateResult, id string) error {
+	if res.MatchedCount == 0 {
+		return fmt.Errorf("restaurant %s: %w", id, restaurant.ErrNotFound)
+	}
 	return nil
 }
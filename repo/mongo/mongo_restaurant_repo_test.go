@@ -0,0 +1,65 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	restaurant "github.com/testingrepo/domain"
+	"github.com/testingrepo/repo/repotest"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// newTestClient connects to the Mongo instance in MONGO_TEST_URI (e.g. a local
+// mongod or container) and skips the test when it is not set.
+func newTestClient(t *testing.T) *MongoClient {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+	client, err := ConnectMongo(MongoConfig{URI: uri, Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+	return client
+}
+
+// newTestRepo returns a repository on a fresh database dropped when the test ends.
+func newTestRepo(t *testing.T, client *MongoClient) *RestaurantRepo {
+	t.Helper()
+	db := client.WithDatabase(fmt.Sprintf("repotest_%d", time.Now().UnixNano()))
+	t.Cleanup(func() { _ = db.DB.Drop(context.Background()) })
+	return &RestaurantRepo{Database: db}
+}
+
+// newMockRepo returns a repository on the mock deployment of mt. It answers with the
+// responses queued by mt.AddMockResponses and records the commands it is sent, so the
+// generated queries can be checked without a server.
+func newMockRepo(mt *mtest.T) *RestaurantRepo {
+	return &RestaurantRepo{Database: newMongoClient(mt.Client, MongoConfig{Database: mt.DB.Name()})}
+}
+
+// normalize round-trips doc through BSON, so documents built from bson.M compare with
+// the recorded commands regardless of key order.
+func normalize(t *testing.T, doc any) bson.M {
+	t.Helper()
+	raw, err := bson.Marshal(doc)
+	require.NoError(t, err)
+	var m bson.M
+	require.NoError(t, bson.Unmarshal(raw, &m))
+	return m
+}
+
+func TestRestaurantRepoContract(t *testing.T) {
+	client := newTestClient(t)
+	repotest.RunRestaurantRepositorySuite(t, func(t *testing.T) restaurant.RestaurantRepository {
+		return newTestRepo(t, client)
+	})
+}
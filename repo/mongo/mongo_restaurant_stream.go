@@ -0,0 +1,32 @@
+package mongo
+
+import (
+	"context"
+	"iter"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (r *RestaurantRepo) StreamAll(ctx context.Context) iter.Seq2[*restaurant.Restaurant, error] {
+	return r.stream(ctx, bson.M{})
+}
+
+func (r *RestaurantRepo) StreamByName(ctx context.Context, name string) iter.Seq2[*restaurant.Restaurant, error] {
+	return r.stream(ctx, nameFilter(name))
+}
+
+func (r *RestaurantRepo) stream(ctx context.Context, filter bson.M) iter.Seq2[*restaurant.Restaurant, error] {
+	return func(yield func(*restaurant.Restaurant, error) bool) {
+		for doc, err := range StreamMany[restaurant.RestaurantBSON](ctx, r.Database, RESTAURANT_COLLECTION, filter) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(doc.RestaurantFromBSONToDTO(), nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package mongo
+
+import (
+	"context"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var _ restaurant.RestaurantTextSearcher = (*RestaurantRepo)(nil)
+
+var fieldMenuItemDescription = bsonPath("Menu.Description")
+
+// scoreField holds the textScore. Like distanceField it is not part of RestaurantBSON.
+const scoreField = "_score"
+
+// languageOverrideField replaces the default "language" override key of the text
+// index, so a future "language" field cannot change how documents are indexed.
+const languageOverrideField = "_language"
+
+type restaurantScoreBSON struct {
+	restaurant.RestaurantBSON `bson:",inline"`
+	Score                     float64 `bson:"_score"`
+}
+
+// TextIndex is the text index SearchText requires, weighted like the memory repository.
+func TextIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{
+			{Key: fieldName, Value: "text"},
+			{Key: fieldMenuItemName, Value: "text"},
+			{Key: fieldMenuItemDescription, Value: "text"},
+		},
+		Options: options.Index().
+			SetName("restaurant_text").
+			SetWeights(bson.D{
+				{Key: fieldName, Value: restaurant.TextWeightName},
+				{Key: fieldMenuItemName, Value: restaurant.TextWeightMenuItemName},
+				{Key: fieldMenuItemDescription, Value: restaurant.TextWeightMenuItemDescription},
+			}).
+			SetDefaultLanguage("english").
+			SetLanguageOverride(languageOverrideField),
+	}
+}
+
+// SearchText runs a $text query sorted by textScore. It needs TextIndex, created by
+// EnsureIndexes.
+func (r *RestaurantRepo) SearchText(ctx context.Context, q restaurant.TextQuery) ([]*restaurant.RestaurantTextMatch, error) {
+	q = q.Normalize()
+	if _, err := q.Parse(); err != nil {
+		return nil, err
+	}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{scoreField: score}).
+		SetSort(bson.D{{Key: scoreField, Value: score}}).
+		SetLimit(int64(q.Limit))
+	var docs []*restaurantScoreBSON
+	if err := r.Database.FindMany(ctx, RESTAURANT_COLLECTION, textFilter(q), &docs, opts); err != nil {
+		return nil, err
+	}
+
+	results := make([]*restaurant.RestaurantTextMatch, len(docs))
+	for i, doc := range docs {
+		results[i] = &restaurant.RestaurantTextMatch{
+			Restaurant: doc.RestaurantFromBSONToDTO(),
+			Score:      doc.Score,
+		}
+	}
+	return results, nil
+}
+
+func textFilter(q restaurant.TextQuery) bson.M {
+	return bson.M{"$text": bson.M{"$search": q.Search, "$language": q.Language}}
+}
@@ -0,0 +1,55 @@
+package mongo
+
+import (
+	"context"
+
+	restaurant "github.com/testingrepo/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+)
+
+var (
+	_ restaurant.UnitOfWork = (*MongoClient)(nil)
+	_ restaurant.UnitOfWork = (*RestaurantRepo)(nil)
+)
+
+// defaultTransactionOptions makes committed writes durable on a majority of the
+// replica set and reads within the transaction see a consistent snapshot.
+func defaultTransactionOptions() *options.TransactionOptions {
+	return options.Transaction().
+		SetReadConcern(readconcern.Snapshot()).
+		SetWriteConcern(writeconcern.Majority())
+}
+
+// WithTransaction runs fn in a transaction on a new session. The session travels in
+// the context handed to fn, so every MongoClient operation called with it, including
+// those of RestaurantRepo, takes part in the transaction. The driver retries fn on
+// transient transaction errors and retries the commit when its outcome is unknown.
+// When ctx already carries a session, fn joins it instead of starting a new one.
+// Transactions need a replica set or sharded cluster.
+func (m *MongoClient) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	release, err := m.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return m.Client.UseSession(ctx, func(sc mongo.SessionContext) error {
+		_, err := sc.WithTransaction(sc, func(sc mongo.SessionContext) (interface{}, error) {
+			return nil, fn(sc)
+		}, defaultTransactionOptions())
+		return err
+	})
+}
+
+// WithTransaction runs fn in a transaction on the repository's connection; see
+// MongoClient.WithTransaction.
+func (r *RestaurantRepo) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return r.Database.WithTransaction(ctx, fn)
+}
@@ -0,0 +1,50 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	restaurant "github.com/testingrepo/domain"
+	"github.com/testingrepo/repo/repotest"
+)
+
+func TestRestaurantRepoWithTransaction(t *testing.T) {
+	repo := newTestRepo(t, newTestClient(t))
+	ctx := context.Background()
+	// Transactions cannot create collections on older servers.
+	require.NoError(t, repo.Database.DB.CreateCollection(ctx, RESTAURANT_COLLECTION))
+
+	errAbort := errors.New("abort")
+	err := repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.InsertRestaurant(ctx, repotest.NewRestaurant("r1", "Rolled Back")); err != nil {
+			return err
+		}
+		return errAbort
+	})
+	if err != nil && strings.Contains(err.Error(), "replica set") {
+		t.Skip("transactions need a replica set")
+	}
+	assert.ErrorIs(t, err, errAbort)
+	found, err := repo.FindByName(ctx, "Rolled Back")
+	require.NoError(t, err)
+	assert.Empty(t, found)
+
+	err = repo.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repo.InsertRestaurant(ctx, repotest.NewRestaurant("r2", "Committed")); err != nil {
+			return err
+		}
+		// Nested calls join the outer transaction.
+		return repo.WithTransaction(ctx, func(ctx context.Context) error {
+			return repo.AddRating(ctx, "r2", restaurant.Rating{Score: 5, User: "ida"})
+		})
+	})
+	require.NoError(t, err)
+	found, err = repo.FindByName(ctx, "Committed")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Len(t, found[0].Ratings, 2)
+}
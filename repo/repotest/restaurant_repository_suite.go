@@ -0,0 +1,429 @@
+// Package repotest provides a conformance suite every domain.RestaurantRepository
+// implementation is expected to pass.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testingrepo/domain"
+)
+
+// RepositoryFactory returns an empty repository. It is called once per subtest.
+type RepositoryFactory func(t *testing.T) domain.RestaurantRepository
+
+// IndexEnsurer is implemented by repositories that need indexes before their optional
+// capabilities work. The suite calls EnsureIndexes on every new repository.
+type IndexEnsurer interface {
+	EnsureIndexes(ctx context.Context) error
+}
+
+// RunRestaurantRepositorySuite runs the contract tests against repositories built by newRepo.
+func RunRestaurantRepositorySuite(t *testing.T, newRepo RepositoryFactory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, repo domain.RestaurantRepository)
+	}{
+		{"InsertAndFindByName", testInsertAndFindByName},
+		{"InsertAssignsID", testInsertAssignsID},
+		{"InsertDuplicateID", testInsertDuplicateID},
+		{"FindUnknownReturnsEmpty", testFindUnknownReturnsEmpty},
+		{"FindByAddress", testFindByAddress},
+		{"FindByOwner", testFindByOwner},
+		{"FindByRating", testFindByRating},
+		{"FindByMenuItem", testFindByMenuItem},
+		{"FindNear", testFindNear},
+		{"SearchText", testSearchText},
+		{"Analytics", testAnalytics},
+		{"UpdateMenu", testUpdateMenu},
+		{"AddRating", testAddRating},
+		{"UpdateEmployee", testUpdateEmployee},
+		{"WritesNotFound", testWritesNotFound},
+		{"ConcurrentAddRating", testConcurrentAddRating},
+		{"ContextCancelled", testContextCancelled},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+			if indexer, ok := repo.(IndexEnsurer); ok {
+				require.NoError(t, indexer.EnsureIndexes(context.Background()))
+			}
+			tc.run(t, repo)
+		})
+	}
+}
+
+// NewRestaurant returns a fully populated restaurant with the given ID and name.
+func NewRestaurant(id, name string) *domain.Restaurant {
+	return &domain.Restaurant{
+		ID:    id,
+		Name:  name,
+		Email: "contact@" + id + ".example",
+		Age:   7,
+		Address: domain.Address{
+			Street: "1 Main St",
+			City:   "Springfield",
+			State:  "IL",
+			Zip:    "62701",
+		},
+		Owners:    []string{"Ann Owner"},
+		Employees: []domain.Employee{{Name: "Carl", Role: "Chef", Age: 41}},
+		Menu:      []domain.MenuItem{{Name: "Soup", Description: "Daily soup", Price: 6.5}},
+		Ratings:   []domain.Rating{{Score: 4, User: "dave", Note: "Good"}},
+	}
+}
+
+func insert(t *testing.T, repo domain.RestaurantRepository, rests ...*domain.Restaurant) {
+	t.Helper()
+	for _, r := range rests {
+		require.NoError(t, repo.InsertRestaurant(context.Background(), r))
+	}
+}
+
+func findOne(t *testing.T, repo domain.RestaurantRepository, name string) *domain.Restaurant {
+	t.Helper()
+	found, err := repo.FindByName(context.Background(), name)
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	return found[0]
+}
+
+func testInsertAndFindByName(t *testing.T, repo domain.RestaurantRepository) {
+	want := NewRestaurant("r1", "Soup Place")
+	insert(t, repo, want, NewRestaurant("r2", "Other Place"))
+
+	assert.Equal(t, NewRestaurant("r1", "Soup Place"), findOne(t, repo, "Soup Place"))
+}
+
+func testInsertAssignsID(t *testing.T, repo domain.RestaurantRepository) {
+	r := NewRestaurant("", "No ID")
+	insert(t, repo, r)
+
+	assert.NotEmpty(t, r.ID)
+	assert.Equal(t, r.ID, findOne(t, repo, "No ID").ID)
+}
+
+func testInsertDuplicateID(t *testing.T, repo domain.RestaurantRepository) {
+	insert(t, repo, NewRestaurant("r1", "First"))
+
+	assert.Error(t, repo.InsertRestaurant(context.Background(), NewRestaurant("r1", "Second")))
+}
+
+func testFindUnknownReturnsEmpty(t *testing.T, repo domain.RestaurantRepository) {
+	ctx := context.Background()
+	insert(t, repo, NewRestaurant("r1", "Soup Place"))
+
+	byName, err := repo.FindByName(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, byName)
+	byOwner, err := repo.FindByOwner(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, byOwner)
+	byRating, err := repo.FindByRating(ctx, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, byRating)
+	byItem, err := repo.FindByMenuItem(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Empty(t, byItem)
+}
+
+func testFindByAddress(t *testing.T, repo domain.RestaurantRepository) {
+	ctx := context.Background()
+	side := NewRestaurant("r2", "Elsewhere")
+	side.Address.Street = "9 Side St"
+	chicago := NewRestaurant("r3", "Windy")
+	chicago.Address = domain.Address{Street: "5 Lake Shore Dr", City: "Chicago", State: "IL", Zip: "60611"}
+	austin := NewRestaurant("r4", "Tex")
+	austin.Address = domain.Address{Street: "1 Main St", City: "Austin", State: "TX", Zip: "73301"}
+	insert(t, repo, NewRestaurant("r1", "Soup Place"), side, chicago, austin)
+
+	for _, tc := range []struct {
+		name     string
+		criteria domain.Address
+		want     []string
+	}{
+		{"Street", domain.Address{Street: "9 Side St"}, []string{"r2"}},
+		{"CityIgnoresCase", domain.Address{City: "CHICAGO"}, []string{"r3"}},
+		{"State", domain.Address{State: "il"}, []string{"r1", "r2", "r3"}},
+		{"ZipPrefix", domain.Address{Zip: "627"}, []string{"r1", "r2"}},
+		{"Combined", domain.Address{Street: "1 main st", State: "TX"}, []string{"r4"}},
+		{"NoMatch", domain.Address{City: "Chic"}, nil},
+		{"RegexIsLiteral", domain.Address{City: ".*"}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			found, err := repo.FindByAddress(ctx, tc.criteria)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.want, ids(found))
+		})
+	}
+
+	_, err := repo.FindByAddress(ctx, domain.Address{})
+	assert.ErrorIs(t, err, domain.ErrEmptyCriteria)
+}
+
+func testFindByOwner(t *testing.T, repo domain.RestaurantRepository) {
+	shared := NewRestaurant("r2", "Shared")
+	shared.Owners = []string{"Zed", "Ann Owner"}
+	solo := NewRestaurant("r3", "Solo")
+	solo.Owners = []string{"Zed"}
+	insert(t, repo, NewRestaurant("r1", "Soup Place"), shared, solo)
+
+	found, err := repo.FindByOwner(context.Background(), "Ann Owner")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"r1", "r2"}, ids(found))
+}
+
+func testFindByRating(t *testing.T, repo domain.RestaurantRepository) {
+	great := NewRestaurant("r2", "Great")
+	great.Ratings = append(great.Ratings, domain.Rating{Score: 5, User: "eve"})
+	insert(t, repo, NewRestaurant("r1", "Soup Place"), great)
+
+	found, err := repo.FindByRating(context.Background(), 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r2"}, ids(found))
+}
+
+func testFindByMenuItem(t *testing.T, repo domain.RestaurantRepository) {
+	pizza := NewRestaurant("r2", "Pizza")
+	pizza.Menu = append(pizza.Menu, domain.MenuItem{Name: "Margherita", Price: 11})
+	insert(t, repo, NewRestaurant("r1", "Soup Place"), pizza)
+
+	found, err := repo.FindByMenuItem(context.Background(), "Margherita")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"r2"}, ids(found))
+}
+
+// capability returns repo as the optional interface C, skipping the test when the
+// repository does not implement it.
+func capability[C any](t *testing.T, repo domain.RestaurantRepository) C {
+	t.Helper()
+	c, ok := repo.(C)
+	if !ok {
+		t.Skipf("repository does not implement %s", reflect.TypeFor[C]())
+	}
+	return c
+}
+
+func testFindNear(t *testing.T, repo domain.RestaurantRepository) {
+	geo := capability[domain.RestaurantGeoReader](t, repo)
+	ctx := context.Background()
+
+	// Springfield, IL: the old state capitol, a spot ~2 km away and Chicago, ~300 km away.
+	capitol := domain.GeoPoint{Lat: 39.8003, Lng: -89.6437}
+	far := NewRestaurant("r1", "Far")
+	far.Address.Location = &domain.GeoPoint{Lat: 39.7817, Lng: -89.6501}
+	nearby := NewRestaurant("r2", "Nearby")
+	nearby.Address.Location = &domain.GeoPoint{Lat: 39.8010, Lng: -89.6440}
+	chicago := NewRestaurant("r3", "Chicago")
+	chicago.Address.Location = &domain.GeoPoint{Lat: 41.8781, Lng: -87.6298}
+	insert(t, repo, far, nearby, chicago, NewRestaurant("r4", "Not Geocoded"))
+
+	found, err := geo.FindNear(ctx, capitol, 5000)
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "r2", found[0].Restaurant.ID)
+	assert.Equal(t, "r1", found[1].Restaurant.ID)
+	assert.InDelta(t, domain.HaversineMeters(capitol, *nearby.Address.Location), found[0].DistanceMeters, 1)
+	assert.InDelta(t, domain.HaversineMeters(capitol, *far.Address.Location), found[1].DistanceMeters, 1)
+	assert.Equal(t, *far.Address.Location, *found[1].Restaurant.Address.Location)
+
+	_, err = geo.FindNear(ctx, domain.GeoPoint{Lat: 91}, 5000)
+	assert.ErrorIs(t, err, domain.ErrInvalidPoint)
+	for _, radius := range []float64{-1, math.NaN(), math.Inf(1)} {
+		_, err = geo.FindNear(ctx, capitol, radius)
+		assert.ErrorIs(t, err, domain.ErrInvalidRadius, "radius %v", radius)
+	}
+}
+
+func testSearchText(t *testing.T, repo domain.RestaurantRepository) {
+	searcher := capability[domain.RestaurantTextSearcher](t, repo)
+	ctx := context.Background()
+
+	pizzeria := NewRestaurant("r1", "Pizza Palace")
+	pizzeria.Menu = []domain.MenuItem{{Name: "Margherita", Description: "Classic pizza with tomato and basil"}}
+	tacos := NewRestaurant("r2", "Taco Town")
+	tacos.Menu = []domain.MenuItem{{Name: "Pizza Taco", Description: "Fusion street food"}}
+	burgers := NewRestaurant("r3", "Burger Barn")
+	burgers.Menu = []domain.MenuItem{{Name: "Fries", Description: "Better than pizza"}}
+	insert(t, repo, pizzeria, tacos, burgers)
+
+	search := func(q domain.TextQuery) []string {
+		t.Helper()
+		found, err := searcher.SearchText(ctx, q)
+		require.NoError(t, err)
+		ids := make([]string, len(found))
+		for i, m := range found {
+			ids[i] = m.Restaurant.ID
+			if i > 0 {
+				assert.GreaterOrEqual(t, found[i-1].Score, m.Score, "results are sorted by score")
+			}
+		}
+		return ids
+	}
+
+	cases := []struct {
+		name  string
+		query domain.TextQuery
+		want  []string
+	}{
+		{"name outranks menu item outranks description", domain.TextQuery{Search: "pizza"}, []string{"r1", "r2", "r3"}},
+		{"case insensitive", domain.TextQuery{Search: "PIZZA"}, []string{"r1", "r2", "r3"}},
+		{"phrase", domain.TextQuery{Search: `"pizza taco"`}, []string{"r2"}},
+		{"negation", domain.TextQuery{Search: "pizza -taco"}, []string{"r1", "r3"}},
+		{"stemming", domain.TextQuery{Search: "burgers"}, []string{"r3"}},
+		{"no stemming", domain.TextQuery{Search: "burgers", Language: "none"}, []string{}},
+		{"limit", domain.TextQuery{Search: "pizza", Limit: 1}, []string{"r1"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, search(tc.query))
+		})
+	}
+
+	_, err := searcher.SearchText(ctx, domain.TextQuery{Search: "-pizza"})
+	assert.ErrorIs(t, err, domain.ErrInvalidTextSearch)
+	_, err = searcher.SearchText(ctx, domain.TextQuery{Search: "pizza", Language: "klingon"})
+	assert.ErrorIs(t, err, domain.ErrInvalidTextSearch)
+}
+
+func testAnalytics(t *testing.T, repo domain.RestaurantRepository) {
+	analytics := capability[domain.RestaurantAnalyticsReader](t, repo)
+	ctx := context.Background()
+
+	best := NewRestaurant("r1", "Best")
+	best.Ratings = []domain.Rating{{Score: 5}, {Score: 4}}
+	best.Menu = []domain.MenuItem{{Name: "Soup", Price: 6.5}, {Name: "Stew", Price: 12}, {Name: "Tea", Price: 2}}
+	popular := NewRestaurant("r2", "Popular")
+	popular.Ratings = []domain.Rating{{Score: 3}, {Score: 4}, {Score: 5}, {Score: 4}}
+	tied := NewRestaurant("r3", "Tied")
+	tied.Ratings = []domain.Rating{{Score: 5}, {Score: 3}}
+	unrated := NewRestaurant("r4", "Unrated")
+	unrated.Ratings = nil
+	unrated.Menu = nil
+	elsewhere := NewRestaurant("r5", "Elsewhere")
+	elsewhere.Address.City = "Shelbyville"
+	elsewhere.Ratings = []domain.Rating{{Score: 5}}
+	insert(t, repo, best, popular, tied, unrated, elsewhere)
+
+	summary, err := analytics.AverageRating(ctx, "r1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RatingSummary{RestaurantID: "r1", Average: 4.5, Count: 2}, *summary)
+	summary, err = analytics.AverageRating(ctx, "r4")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RatingSummary{RestaurantID: "r4"}, *summary)
+
+	top, err := analytics.TopRatedByCity(ctx, "springfield", 0)
+	require.NoError(t, err)
+	var ranked []string
+	for _, r := range top {
+		ranked = append(ranked, r.Restaurant.ID)
+	}
+	assert.Equal(t, []string{"r1", "r2", "r3"}, ranked, "by average, then count, then ID; unrated restaurants are left out")
+	assert.Equal(t, 4.0, top[1].Average)
+	assert.Equal(t, 4, top[1].Count)
+	assert.Equal(t, best.Menu, top[0].Restaurant.Menu)
+
+	top, err = analytics.TopRatedByCity(ctx, "Springfield", 1)
+	require.NoError(t, err)
+	require.Len(t, top, 1)
+	assert.Equal(t, "r1", top[0].Restaurant.ID)
+
+	stats, err := analytics.MenuPriceStats(ctx, "r1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PriceStats{RestaurantID: "r1", Min: 2, Max: 12, Average: 20.5 / 3, Count: 3}, *stats)
+	stats, err = analytics.MenuPriceStats(ctx, "r4")
+	require.NoError(t, err)
+	assert.Equal(t, domain.PriceStats{RestaurantID: "r4"}, *stats)
+
+	_, err = analytics.AverageRating(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = analytics.MenuPriceStats(ctx, "missing")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = analytics.TopRatedByCity(ctx, "", 10)
+	assert.ErrorIs(t, err, domain.ErrEmptyCriteria)
+}
+
+func testUpdateMenu(t *testing.T, repo domain.RestaurantRepository) {
+	insert(t, repo, NewRestaurant("r1", "Soup Place"))
+	menu := []domain.MenuItem{{Name: "Stew", Price: 9}, {Name: "Bread", Price: 2}}
+
+	require.NoError(t, repo.UpdateMenu(context.Background(), "r1", menu))
+	assert.Equal(t, menu, findOne(t, repo, "Soup Place").Menu)
+}
+
+func testAddRating(t *testing.T, repo domain.RestaurantRepository) {
+	insert(t, repo, NewRestaurant("r1", "Soup Place"))
+	rating := domain.Rating{Score: 2, User: "fay", Note: "Cold"}
+
+	require.NoError(t, repo.AddRating(context.Background(), "r1", rating))
+	ratings := findOne(t, repo, "Soup Place").Ratings
+	require.Len(t, ratings, 2)
+	assert.Equal(t, rating, ratings[1])
+}
+
+func testUpdateEmployee(t *testing.T, repo domain.RestaurantRepository) {
+	ctx := context.Background()
+	insert(t, repo, NewRestaurant("r1", "Soup Place"))
+
+	promoted := domain.Employee{Name: "Carl", Role: "Head Chef", Age: 42}
+	require.NoError(t, repo.UpdateEmployee(ctx, "r1", promoted))
+	hired := domain.Employee{Name: "Gus", Role: "Waiter", Age: 22}
+	require.NoError(t, repo.UpdateEmployee(ctx, "r1", hired))
+
+	assert.Equal(t, []domain.Employee{promoted, hired}, findOne(t, repo, "Soup Place").Employees)
+}
+
+func testWritesNotFound(t *testing.T, repo domain.RestaurantRepository) {
+	ctx := context.Background()
+
+	assert.ErrorIs(t, repo.UpdateMenu(ctx, "missing", nil), domain.ErrNotFound)
+	assert.ErrorIs(t, repo.AddRating(ctx, "missing", domain.Rating{Score: 1}), domain.ErrNotFound)
+	assert.ErrorIs(t, repo.UpdateEmployee(ctx, "missing", domain.Employee{Name: "x"}), domain.ErrNotFound)
+}
+
+func testConcurrentAddRating(t *testing.T, repo domain.RestaurantRepository) {
+	insert(t, repo, NewRestaurant("r1", "Soup Place"))
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- repo.AddRating(context.Background(), "r1", domain.Rating{Score: 3, User: fmt.Sprintf("user-%d", i)})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	assert.Len(t, findOne(t, repo, "Soup Place").Ratings, writers+1)
+}
+
+func testContextCancelled(t *testing.T, repo domain.RestaurantRepository) {
+	insert(t, repo, NewRestaurant("r1", "Soup Place"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := repo.FindByName(ctx, "Soup Place")
+	assert.Error(t, err)
+	assert.Error(t, repo.InsertRestaurant(ctx, NewRestaurant("r2", "Late")))
+	assert.Error(t, repo.AddRating(ctx, "r1", domain.Rating{Score: 1}))
+}
+
+func ids(rests []*domain.Restaurant) []string {
+	out := make([]string, len(rests))
+	for i, r := range rests {
+		out[i] = r.ID
+	}
+	return out
+}
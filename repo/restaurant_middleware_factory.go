@@ -3,21 +3,89 @@ package repo
 import (
 	"context"
 	"errors"
+	"fmt"
+	"iter"
 	"log"
-	"sync"
 	"time"
 
 	"github.com/testingrepo/domain"
 	"github.com/testingrepo/infra"
 )
 
-var ErrNotFound = errors.New("not found")
+var (
+	ErrNotFound     = domain.ErrNotFound
+	ErrNotSupported = errors.New("operation not supported by repository")
+	ErrInvalidChain = errors.New("invalid middleware chain")
+)
+
+// Meta keys set by the paged finders.
+var (
+	PAGE_NEXT_CURSOR = "next_cursor"
+	PAGE_HAS_MORE    = "has_more"
+	PAGE_TOTAL_COUNT = "total_count"
+)
+
+// NEAR_DISTANCES is set by FindRestaurantsNear: the distance in meters of each
+// restaurant, aligned with Data.
+var NEAR_DISTANCES = "distances"
+
+// TEXT_SCORES is set by SearchRestaurantsText: the relevance score of each
+// restaurant, aligned with Data.
+var TEXT_SCORES = "scores"
+
+// NearInput is the input of FindRestaurantsNear.
+type NearInput struct {
+	Point        domain.GeoPoint
+	RadiusMeters float64
+}
+
+// TopRatedInput is the input of TopRatedByCity.
+type TopRatedInput struct {
+	City  string
+	Limit int
+}
+
+// PagedInput is the input of the paged finders: the finder argument plus the page to fetch.
+type PagedInput[T any] struct {
+	Query T
+	Page  domain.PageRequest
+}
 
 var (
 	retries    = 2
 	retryDelay = 100 * time.Millisecond
 )
 
+// permanentErrors are the failures retrying cannot fix: the operation is missing, the
+// restaurant does not exist or the input is invalid.
+var permanentErrors = []error{
+	ErrNotSupported,
+	domain.ErrNotFound,
+	domain.ErrAlreadyExists,
+	domain.ErrEmptyCriteria,
+	domain.ErrInvalidQuery,
+	domain.ErrInvalidCursor,
+	domain.ErrInvalidPoint,
+	domain.ErrInvalidRadius,
+	domain.ErrInvalidTextSearch,
+	context.Canceled,
+	context.DeadlineExceeded,
+}
+
+// IsRetryable reports whether a failed read may succeed when retried. The finder
+// chains only retry these errors.
+func IsRetryable(err error) bool {
+	for _, permanent := range permanentErrors {
+		if errors.Is(err, permanent) {
+			return false
+		}
+	}
+	return true
+}
+
+// DefaultSamplingPolicy samples logging and output callbacks for every request.
+var DefaultSamplingPolicy = infra.SamplingPolicy{Head: infra.AlwaysSample()}
+
 // ////////////////// CALLBACK FUNCTIONS ////////////////////
 // func timerCallback(t time.Duration, e error) {
 // 	log.Printf("TIMER: Operation took %s", t)
@@ -45,43 +113,150 @@ func loggingCallback(ctx context.Context, msg string) {
 
 func maskingCallback(output []*domain.Restaurant) []*domain.Restaurant {
 	for _, r := range output {
-		// Mask email
-		if r.Email != "" {
-			r.Email = "****"
-		}
-		// Mask owner names
-		for j, owner := range r.Owners {
-			if len(owner) > 2 {
-				r.Owners[j] = owner[:1] + "****" + owner[len(owner)-1:]
-			}
+		maskRestaurant(r)
+	}
+	return output
+}
+
+func maskRestaurant(r *domain.Restaurant) *domain.Restaurant {
+	if r == nil {
+		return r
+	}
+	// Mask email
+	if r.Email != "" {
+		r.Email = "****"
+	}
+	// Mask owner names
+	for j, owner := range r.Owners {
+		if len(owner) > 2 {
+			r.Owners[j] = owner[:1] + "****" + owner[len(owner)-1:]
 		}
-		// Mask employee names
-		for j, emp := range r.Employees {
-			if emp.Name != "" && len(emp.Name) > 2 {
-				r.Employees[j].Name = emp.Name[:1] + "****" + emp.Name[len(emp.Name)-1:]
-			}
+	}
+	// Mask employee names
+	for j, emp := range r.Employees {
+		if emp.Name != "" && len(emp.Name) > 2 {
+			r.Employees[j].Name = emp.Name[:1] + "****" + emp.Name[len(emp.Name)-1:]
 		}
 	}
-	return output
+	return r
 }
 
 //////////////////////////////////////////////////////////
 
+// Operation names used to describe the factory chains.
+const (
+	OP_FIND_BY_NAME      = "FindByName"
+	OP_FIND_BY_ADDRESS   = "FindByAddress"
+	OP_FIND_BY_OWNER     = "FindByOwner"
+	OP_FIND_BY_RATING    = "FindByRating"
+	OP_FIND_BY_MENU_ITEM = "FindByMenuItem"
+
+	OP_FIND        = "Find"
+	OP_FIND_NEAR   = "FindNear"
+	OP_SEARCH_TEXT = "SearchText"
+
+	OP_FIND_BY_NAME_PAGED      = "FindByNamePaged"
+	OP_FIND_BY_OWNER_PAGED     = "FindByOwnerPaged"
+	OP_FIND_BY_RATING_PAGED    = "FindByRatingPaged"
+	OP_FIND_BY_MENU_ITEM_PAGED = "FindByMenuItemPaged"
+
+	OP_AVERAGE_RATING    = "AverageRating"
+	OP_TOP_RATED_BY_CITY = "TopRatedByCity"
+	OP_MENU_PRICE_STATS  = "MenuPriceStats"
+
+	OP_STREAM_ALL     = "StreamAll"
+	OP_STREAM_BY_NAME = "StreamByName"
+)
+
+// FactoryOption customises a RestaurantMiddlewareFactory.
+type FactoryOption func(f *RestaurantMiddlewareFactory)
+
+// WithChain lets an application tweak the default chain of a single operation, e.g.
+// remove Retry from FindByRating. In and Out must match the types of op; Out is
+// inferred from customize. The customiser works on a clone of the default chain.
+// NewRestaurantMiddlewareFactory fails with ErrInvalidChain when op is unknown, the
+// types do not match or the customiser fails.
+func WithChain[In, Out any](op string, customize func(b *infra.MiddlewareBuilder[In, Out]) error) FactoryOption {
+	return func(f *RestaurantMiddlewareFactory) {
+		f.customizers[op] = customize
+	}
+}
+
+// WithSampling samples the Logging and OutputResult middlewares with policy instead
+// of DefaultSamplingPolicy, e.g. to only log errors and slow calls in production.
+func WithSampling(policy infra.SamplingPolicy) FactoryOption {
+	return func(f *RestaurantMiddlewareFactory) {
+		f.sampling = policy
+	}
+}
+
+// WithFallback answers the operations from fallback, e.g. a cache or a static snapshot
+// repository, when the repository still fails after retries with an error accepted by
+// classify (nil accepts every error). Fallback results are marked with infra.DEGRADED
+// in Meta and masked like any other result.
+func WithFallback(fallback domain.RestaurantReader, classify func(err error) bool) FactoryOption {
+	return func(f *RestaurantMiddlewareFactory) {
+		f.fallback = &RestaurantMiddlewareFactory{RestaurantRepo: fallback}
+		f.classifyFallback = classify
+	}
+}
+
 type RestaurantMiddlewareFactory struct {
-	RestaurantRepo domain.RestaurantReader
-	once           sync.Once
+	RestaurantRepo   domain.RestaurantReader
+	chains           map[string]infra.ChainDescription
+	customizers      map[string]any
+	sampling         infra.SamplingPolicy
+	fallback         *RestaurantMiddlewareFactory
+	classifyFallback func(err error) bool
+	err              error
 
 	FindRestaurantByName     infra.RepoOp[string, []*domain.Restaurant]
-	FindRestaurantByAddress  infra.RepoOp[string, []*domain.Restaurant]
+	FindRestaurantByAddress  infra.RepoOp[domain.Address, []*domain.Restaurant]
 	FindRestaurantByOwner    infra.RepoOp[string, []*domain.Restaurant]
 	FindRestaurantByRating   infra.RepoOp[int, []*domain.Restaurant]
 	FindRestaurantByMenuItem infra.RepoOp[string, []*domain.Restaurant]
+
+	// FindRestaurants fails with ErrNotSupported when the repository is not a domain.RestaurantQuerier.
+	FindRestaurants infra.RepoOp[domain.RestaurantQuery, []*domain.Restaurant]
+
+	// FindRestaurantsNear reports distances under NEAR_DISTANCES in Meta. It fails with
+	// ErrNotSupported when the repository is not a domain.RestaurantGeoReader.
+	FindRestaurantsNear infra.RepoOp[NearInput, []*domain.Restaurant]
+
+	// SearchRestaurantsText reports scores under TEXT_SCORES in Meta. It fails with
+	// ErrNotSupported when the repository is not a domain.RestaurantTextSearcher.
+	SearchRestaurantsText infra.RepoOp[domain.TextQuery, []*domain.Restaurant]
+
+	// Paged finders report page info under PAGE_* keys in Meta. They fail with
+	// ErrNotSupported when the repository is not a domain.RestaurantPagedReader.
+	FindRestaurantByNamePaged     infra.RepoOp[PagedInput[string], []*domain.Restaurant]
+	FindRestaurantByOwnerPaged    infra.RepoOp[PagedInput[string], []*domain.Restaurant]
+	FindRestaurantByRatingPaged   infra.RepoOp[PagedInput[int], []*domain.Restaurant]
+	FindRestaurantByMenuItemPaged infra.RepoOp[PagedInput[string], []*domain.Restaurant]
+
+	// Analytics fail with ErrNotSupported when the repository is not a
+	// domain.RestaurantAnalyticsReader. Only TopRatedByCity returns restaurants to mask.
+	AverageRating  infra.RepoOp[string, *domain.RatingSummary]
+	TopRatedByCity infra.RepoOp[TopRatedInput, []*domain.RatedRestaurant]
+	MenuPriceStats infra.RepoOp[string, *domain.PriceStats]
+
+	// Streams yield ErrNotSupported when the repository is not a domain.RestaurantStreamer.
+	StreamAllRestaurants    infra.StreamOp[struct{}, *domain.Restaurant]
+	StreamRestaurantsByName infra.StreamOp[string, *domain.Restaurant]
 }
 
-func NewRestaurantMiddlewareFactory(repo domain.RestaurantReader) *RestaurantMiddlewareFactory {
+// NewRestaurantMiddlewareFactory builds every operation chain. It fails with
+// ErrInvalidChain on a misconfigured WithChain or WithFallback instead of serving a
+// chain the caller did not ask for.
+func NewRestaurantMiddlewareFactory(repo domain.RestaurantReader, opts ...FactoryOption) (*RestaurantMiddlewareFactory, error) {
 	f := &RestaurantMiddlewareFactory{
 		RestaurantRepo: repo,
-		once:           sync.Once{},
+		chains:         make(map[string]infra.ChainDescription),
+		customizers:    make(map[string]any),
+		sampling:       DefaultSamplingPolicy,
+	}
+	for _, opt := range opts {
+		opt(f)
 	}
 
 	f.initFindByName()
@@ -89,7 +264,94 @@ func NewRestaurantMiddlewareFactory(repo domain.RestaurantReader) *RestaurantMid
 	f.initFindByOwner()
 	f.initFindByRating()
 	f.initFindByMenuItem()
-	return f
+	f.initFind()
+	f.initFindNear()
+	f.initSearchText()
+	f.initPaged()
+	f.initAnalytics()
+	f.initStreams()
+	if f.err != nil {
+		return nil, f.err
+	}
+	for op := range f.customizers {
+		return nil, fmt.Errorf("%w: customiser for unknown operation %s", ErrInvalidChain, op)
+	}
+	return f, nil
+}
+
+// Describe returns the composed middleware chain of every operation, keyed by operation name.
+func (f *RestaurantMiddlewareFactory) Describe() map[string]infra.ChainDescription {
+	out := make(map[string]infra.ChainDescription, len(f.chains))
+	for op, d := range f.chains {
+		out[op] = d
+	}
+	return out
+}
+
+// DefaultRestaurantChain returns the middleware chain shared by every restaurant finder.
+func DefaultRestaurantChain[In any]() *infra.MiddlewareBuilder[In, []*domain.Restaurant] {
+	return restaurantChain[In](DefaultSamplingPolicy)
+}
+
+func restaurantChain[In any](sampling infra.SamplingPolicy) *infra.MiddlewareBuilder[In, []*domain.Restaurant] {
+	return operationChain[In](sampling, outputCallback, maskingCallback)
+}
+
+// operationChain returns the middleware chain shared by every operation. output reports
+// the results and mask hides personal data in them; either may be nil, e.g. for results
+// without restaurants.
+func operationChain[In, Out any](sampling infra.SamplingPolicy, output func(Out, map[string]interface{}, error), mask func(Out) Out) *infra.MiddlewareBuilder[In, Out] {
+	builder := &infra.MiddlewareBuilder[In, Out]{}
+	builder.AddGate(infra.MW_LOGGING, infra.Sampled(sampling, infra.Logging[In, Out](loggingCallback)), infra.IsLoggingDisabled)
+	builder.AddGate(infra.MW_TIMER, infra.Timer[In, Out](), infra.IsTimingDisabled)
+	if output != nil {
+		builder.AddGate(infra.MW_OUTPUT_RESULT, infra.Sampled(sampling, infra.OutputResult[In](output)), infra.IsOutputResultDisabled)
+	}
+	if mask != nil {
+		builder.AddGate(infra.MW_MASK_OUTPUT, infra.MaskOutput[In](mask), infra.IsMaskingDisabled)
+	}
+	builder.AddGate(infra.MW_RETRY, infra.RetryIf[In, Out](retries, retryDelay, IsRetryable), infra.IsRetryDisabled)
+	return builder
+}
+
+// buildChain composes the default restaurant chain around the finder bind returns.
+func buildChain[In any](f *RestaurantMiddlewareFactory, op string, bind func(f *RestaurantMiddlewareFactory) infra.RepoOp[In, []*domain.Restaurant]) infra.RepoOp[In, []*domain.Restaurant] {
+	return composeChain(f, op, restaurantChain[In](f.sampling), bind)
+}
+
+// composeChain applies any customiser registered for op to builder, records the chain
+// description and composes it around the operation bind returns for f. With
+// WithFallback, the same operation bound to the fallback reader answers failed calls.
+// The first configuration error is kept in f.err for NewRestaurantMiddlewareFactory.
+func composeChain[In, Out any](f *RestaurantMiddlewareFactory, op string, builder *infra.MiddlewareBuilder[In, Out], bind func(f *RestaurantMiddlewareFactory) infra.RepoOp[In, Out]) infra.RepoOp[In, Out] {
+	if f.fallback != nil {
+		fallback := infra.Gated(infra.MW_FALLBACK, infra.Fallback(f.classifyFallback, bind(f.fallback)), infra.IsFallbackDisabled)
+		if err := builder.InsertBefore(infra.MW_RETRY, fallback); err != nil {
+			f.fail(fmt.Errorf("%w: %s: cannot add fallback: %w", ErrInvalidChain, op, err))
+		}
+	}
+	if c, ok := f.customizers[op]; ok {
+		delete(f.customizers, op)
+		customize, ok := c.(func(b *infra.MiddlewareBuilder[In, Out]) error)
+		if !ok {
+			f.fail(fmt.Errorf("%w: %s: customiser has the wrong type, want %T", ErrInvalidChain, op, customize))
+		} else if err := customize(builder); err != nil {
+			f.fail(fmt.Errorf("%w: %s: %w", ErrInvalidChain, op, err))
+		}
+	}
+
+	d := builder.Describe()
+	for _, issue := range d.Issues {
+		infraLogger.Error("⚠️ CHAIN %s: %s", op, issue)
+	}
+	f.chains[op] = d
+	return builder.Build(bind(f))
+}
+
+func (f *RestaurantMiddlewareFactory) fail(err error) {
+	if f.err == nil {
+		f.err = err
+	}
 }
 
 func (f *RestaurantMiddlewareFactory) GetRestaurantReader() domain.RestaurantReader {
@@ -108,19 +370,7 @@ func (l logger) Error(msg string, fields ...interface{}) {
 var infraLogger logger = logger{}
 
 func (f *RestaurantMiddlewareFactory) initFindByName() {
-	f.once.Do(func() {
-		builder := infra.MiddlewareBuilder[string, []*domain.Restaurant]{}
-
-		// Add middleware dynamically
-		builder.Add(infra.Gate(infra.Logging[string, []*domain.Restaurant](loggingCallback), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.Timer[string, []*domain.Restaurant](), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.OutputResult[string](outputCallback), infra.IsOutputResultDisabled))
-		builder.Add(infra.Gate(infra.MaskOutput[string](maskingCallback), infra.IsMaskingDisabled))
-		builder.Add(infra.Gate(infra.Retry[string, []*domain.Restaurant](retries, retryDelay), infra.IsRetryDisabled))
-
-		// Build the chain
-		f.FindRestaurantByName = builder.Build(f.bindFindByName())
-	})
+	f.FindRestaurantByName = buildChain(f, OP_FIND_BY_NAME, (*RestaurantMiddlewareFactory).bindFindByName)
 }
 
 // binder: repo method -> RepoOp
@@ -133,23 +383,11 @@ func (f *RestaurantMiddlewareFactory) bindFindByName() infra.RepoOp[string, []*d
 
 // FindByAddress(ctx context.Context, address Address) ([]*Restaurant, error)
 func (f *RestaurantMiddlewareFactory) initFindByAddress() {
-	f.once.Do(func() {
-		builder := infra.MiddlewareBuilder[string, []*domain.Restaurant]{}
-
-		// Add middleware dynamically
-		builder.Add(infra.Gate(infra.Logging[string, []*domain.Restaurant](loggingCallback), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.Timer[string, []*domain.Restaurant](), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.OutputResult[string](outputCallback), infra.IsOutputResultDisabled))
-		builder.Add(infra.Gate(infra.MaskOutput[string](maskingCallback), infra.IsMaskingDisabled))
-		builder.Add(infra.Gate(infra.Retry[string, []*domain.Restaurant](retries, retryDelay), infra.IsRetryDisabled))
-
-		// Build the chain
-		f.FindRestaurantByAddress = builder.Build(f.bindFindByAddress())
-	})
+	f.FindRestaurantByAddress = buildChain(f, OP_FIND_BY_ADDRESS, (*RestaurantMiddlewareFactory).bindFindByAddress)
 }
 
-func (f *RestaurantMiddlewareFactory) bindFindByAddress() infra.RepoOp[string, []*domain.Restaurant] {
-	return func(ctx context.Context, addr string) (infra.OutputWithMeta[[]*domain.Restaurant], error) {
+func (f *RestaurantMiddlewareFactory) bindFindByAddress() infra.RepoOp[domain.Address, []*domain.Restaurant] {
+	return func(ctx context.Context, addr domain.Address) (infra.OutputWithMeta[[]*domain.Restaurant], error) {
 		data, err := f.RestaurantRepo.FindByAddress(ctx, addr)
 		return infra.OutputWithMeta[[]*domain.Restaurant]{Data: data}, err
 	}
@@ -157,17 +395,7 @@ func (f *RestaurantMiddlewareFactory) bindFindByAddress() infra.RepoOp[string, [
 
 // FindByOwner(ctx context.Context, owner string) ([]*Restaurant, error)
 func (f *RestaurantMiddlewareFactory) initFindByOwner() {
-	f.once.Do(func() {
-		builder := infra.MiddlewareBuilder[string, []*domain.Restaurant]{}
-		// Add middleware dynamically
-		builder.Add(infra.Gate(infra.Logging[string, []*domain.Restaurant](loggingCallback), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.Timer[string, []*domain.Restaurant](), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.OutputResult[string](outputCallback), infra.IsOutputResultDisabled))
-		builder.Add(infra.Gate(infra.MaskOutput[string](maskingCallback), infra.IsMaskingDisabled))
-		builder.Add(infra.Gate(infra.Retry[string, []*domain.Restaurant](retries, retryDelay), infra.IsRetryDisabled))
-		// Build the chain
-		f.FindRestaurantByOwner = builder.Build(f.bindFindByOwner())
-	})
+	f.FindRestaurantByOwner = buildChain(f, OP_FIND_BY_OWNER, (*RestaurantMiddlewareFactory).bindFindByOwner)
 }
 
 func (f *RestaurantMiddlewareFactory) bindFindByOwner() infra.RepoOp[string, []*domain.Restaurant] {
@@ -179,17 +407,7 @@ func (f *RestaurantMiddlewareFactory) bindFindByOwner() infra.RepoOp[string, []*
 
 // FindByRating(ctx context.Context, score int) ([]*Restaurant, error)
 func (f *RestaurantMiddlewareFactory) initFindByRating() {
-	f.once.Do(func() {
-		builder := infra.MiddlewareBuilder[int, []*domain.Restaurant]{}
-		// Add middleware dynamically
-		builder.Add(infra.Gate(infra.Logging[int, []*domain.Restaurant](loggingCallback), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.Timer[int, []*domain.Restaurant](), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.OutputResult[int](outputCallback), infra.IsOutputResultDisabled))
-		builder.Add(infra.Gate(infra.MaskOutput[int](maskingCallback), infra.IsMaskingDisabled))
-		builder.Add(infra.Gate(infra.Retry[int, []*domain.Restaurant](retries, retryDelay), infra.IsRetryDisabled))
-		// Build the chain
-		f.FindRestaurantByRating = builder.Build(f.bindFindByRating())
-	})
+	f.FindRestaurantByRating = buildChain(f, OP_FIND_BY_RATING, (*RestaurantMiddlewareFactory).bindFindByRating)
 }
 
 func (f *RestaurantMiddlewareFactory) bindFindByRating() infra.RepoOp[int, []*domain.Restaurant] {
@@ -201,17 +419,7 @@ func (f *RestaurantMiddlewareFactory) bindFindByRating() infra.RepoOp[int, []*do
 
 // FindByMenuItem(ctx context.Context, itemName string) ([]*Restaurant, error)
 func (f *RestaurantMiddlewareFactory) initFindByMenuItem() {
-	f.once.Do(func() {
-		builder := infra.MiddlewareBuilder[string, []*domain.Restaurant]{}
-		// Add middleware dynamically
-		builder.Add(infra.Gate(infra.Logging[string, []*domain.Restaurant](loggingCallback), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.Timer[string, []*domain.Restaurant](), infra.IsTimingDisabled))
-		builder.Add(infra.Gate(infra.OutputResult[string](outputCallback), infra.IsOutputResultDisabled))
-		builder.Add(infra.Gate(infra.MaskOutput[string](maskingCallback), infra.IsMaskingDisabled))
-		builder.Add(infra.Gate(infra.Retry[string, []*domain.Restaurant](retries, retryDelay), infra.IsRetryDisabled))
-		// Build the chain
-		f.FindRestaurantByMenuItem = builder.Build(f.bindFindByMenuItem())
-	})
+	f.FindRestaurantByMenuItem = buildChain(f, OP_FIND_BY_MENU_ITEM, (*RestaurantMiddlewareFactory).bindFindByMenuItem)
 }
 
 func (f *RestaurantMiddlewareFactory) bindFindByMenuItem() infra.RepoOp[string, []*domain.Restaurant] {
@@ -220,3 +428,173 @@ func (f *RestaurantMiddlewareFactory) bindFindByMenuItem() infra.RepoOp[string,
 		return infra.OutputWithMeta[[]*domain.Restaurant]{Data: data}, err
 	}
 }
+
+func (f *RestaurantMiddlewareFactory) initFind() {
+	f.FindRestaurants = buildChain(f, OP_FIND, (*RestaurantMiddlewareFactory).bindFind)
+}
+
+func (f *RestaurantMiddlewareFactory) bindFind() infra.RepoOp[domain.RestaurantQuery, []*domain.Restaurant] {
+	return func(ctx context.Context, q domain.RestaurantQuery) (infra.OutputWithMeta[[]*domain.Restaurant], error) {
+		querier, ok := f.RestaurantRepo.(domain.RestaurantQuerier)
+		if !ok {
+			return infra.OutputWithMeta[[]*domain.Restaurant]{}, ErrNotSupported
+		}
+		data, err := querier.Find(ctx, q)
+		return infra.OutputWithMeta[[]*domain.Restaurant]{Data: data}, err
+	}
+}
+
+func (f *RestaurantMiddlewareFactory) initFindNear() {
+	f.FindRestaurantsNear = buildChain(f, OP_FIND_NEAR, (*RestaurantMiddlewareFactory).bindFindNear)
+}
+
+func (f *RestaurantMiddlewareFactory) bindFindNear() infra.RepoOp[NearInput, []*domain.Restaurant] {
+	return func(ctx context.Context, in NearInput) (infra.OutputWithMeta[[]*domain.Restaurant], error) {
+		geo, ok := f.RestaurantRepo.(domain.RestaurantGeoReader)
+		if !ok {
+			return infra.OutputWithMeta[[]*domain.Restaurant]{}, ErrNotSupported
+		}
+		found, err := geo.FindNear(ctx, in.Point, in.RadiusMeters)
+		if err != nil {
+			return infra.OutputWithMeta[[]*domain.Restaurant]{}, err
+		}
+		data := make([]*domain.Restaurant, len(found))
+		distances := make([]float64, len(found))
+		for i, d := range found {
+			data[i], distances[i] = d.Restaurant, d.DistanceMeters
+		}
+		return infra.OutputWithMeta[[]*domain.Restaurant]{
+			Data: data,
+			Meta: map[string]interface{}{NEAR_DISTANCES: distances},
+		}, nil
+	}
+}
+
+func (f *RestaurantMiddlewareFactory) initSearchText() {
+	f.SearchRestaurantsText = buildChain(f, OP_SEARCH_TEXT, (*RestaurantMiddlewareFactory).bindSearchText)
+}
+
+func (f *RestaurantMiddlewareFactory) bindSearchText() infra.RepoOp[domain.TextQuery, []*domain.Restaurant] {
+	return func(ctx context.Context, q domain.TextQuery) (infra.OutputWithMeta[[]*domain.Restaurant], error) {
+		searcher, ok := f.RestaurantRepo.(domain.RestaurantTextSearcher)
+		if !ok {
+			return infra.OutputWithMeta[[]*domain.Restaurant]{}, ErrNotSupported
+		}
+		found, err := searcher.SearchText(ctx, q)
+		if err != nil {
+			return infra.OutputWithMeta[[]*domain.Restaurant]{}, err
+		}
+		data := make([]*domain.Restaurant, len(found))
+		scores := make([]float64, len(found))
+		for i, m := range found {
+			data[i], scores[i] = m.Restaurant, m.Score
+		}
+		return infra.OutputWithMeta[[]*domain.Restaurant]{
+			Data: data,
+			Meta: map[string]interface{}{TEXT_SCORES: scores},
+		}, nil
+	}
+}
+
+func (f *RestaurantMiddlewareFactory) initPaged() {
+	f.FindRestaurantByNamePaged = buildChain(f, OP_FIND_BY_NAME_PAGED, bindPaged(domain.RestaurantPagedReader.FindByNamePaged))
+	f.FindRestaurantByOwnerPaged = buildChain(f, OP_FIND_BY_OWNER_PAGED, bindPaged(domain.RestaurantPagedReader.FindByOwnerPaged))
+	f.FindRestaurantByRatingPaged = buildChain(f, OP_FIND_BY_RATING_PAGED, bindPaged(domain.RestaurantPagedReader.FindByRatingPaged))
+	f.FindRestaurantByMenuItemPaged = buildChain(f, OP_FIND_BY_MENU_ITEM_PAGED, bindPaged(domain.RestaurantPagedReader.FindByMenuItemPaged))
+}
+
+// bindPaged binds a paged repo method, moving the page info into Meta.
+func bindPaged[T any](find func(domain.RestaurantPagedReader, context.Context, T, domain.PageRequest) (*domain.RestaurantPage, error)) func(f *RestaurantMiddlewareFactory) infra.RepoOp[PagedInput[T], []*domain.Restaurant] {
+	return func(f *RestaurantMiddlewareFactory) infra.RepoOp[PagedInput[T], []*domain.Restaurant] {
+		return bindPagedTo(f, find)
+	}
+}
+
+func bindPagedTo[T any](f *RestaurantMiddlewareFactory, find func(domain.RestaurantPagedReader, context.Context, T, domain.PageRequest) (*domain.RestaurantPage, error)) infra.RepoOp[PagedInput[T], []*domain.Restaurant] {
+	return func(ctx context.Context, in PagedInput[T]) (infra.OutputWithMeta[[]*domain.Restaurant], error) {
+		pager, ok := f.RestaurantRepo.(domain.RestaurantPagedReader)
+		if !ok {
+			return infra.OutputWithMeta[[]*domain.Restaurant]{}, ErrNotSupported
+		}
+		page, err := find(pager, ctx, in.Query, in.Page)
+		if err != nil {
+			return infra.OutputWithMeta[[]*domain.Restaurant]{}, err
+		}
+		return infra.OutputWithMeta[[]*domain.Restaurant]{
+			Data: page.Items,
+			Meta: map[string]interface{}{
+				PAGE_NEXT_CURSOR: page.NextCursor,
+				PAGE_HAS_MORE:    page.HasMore,
+				PAGE_TOTAL_COUNT: page.TotalCount,
+			},
+		}, nil
+	}
+}
+
+func (f *RestaurantMiddlewareFactory) initAnalytics() {
+	f.AverageRating = composeChain(f, OP_AVERAGE_RATING,
+		operationChain[string, *domain.RatingSummary](f.sampling, nil, nil),
+		bindAnalytics(domain.RestaurantAnalyticsReader.AverageRating))
+	f.TopRatedByCity = composeChain(f, OP_TOP_RATED_BY_CITY,
+		operationChain[TopRatedInput](f.sampling, nil, maskRated),
+		bindAnalytics(func(a domain.RestaurantAnalyticsReader, ctx context.Context, in TopRatedInput) ([]*domain.RatedRestaurant, error) {
+			return a.TopRatedByCity(ctx, in.City, in.Limit)
+		}))
+	f.MenuPriceStats = composeChain(f, OP_MENU_PRICE_STATS,
+		operationChain[string, *domain.PriceStats](f.sampling, nil, nil),
+		bindAnalytics(domain.RestaurantAnalyticsReader.MenuPriceStats))
+}
+
+// bindAnalytics binds an analytics repo method.
+func bindAnalytics[In, Out any](read func(domain.RestaurantAnalyticsReader, context.Context, In) (Out, error)) func(f *RestaurantMiddlewareFactory) infra.RepoOp[In, Out] {
+	return func(f *RestaurantMiddlewareFactory) infra.RepoOp[In, Out] {
+		return func(ctx context.Context, in In) (infra.OutputWithMeta[Out], error) {
+			analytics, ok := f.RestaurantRepo.(domain.RestaurantAnalyticsReader)
+			if !ok {
+				return infra.OutputWithMeta[Out]{}, ErrNotSupported
+			}
+			data, err := read(analytics, ctx, in)
+			return infra.OutputWithMeta[Out]{Data: data}, err
+		}
+	}
+}
+
+func maskRated(output []*domain.RatedRestaurant) []*domain.RatedRestaurant {
+	for _, r := range output {
+		maskRestaurant(r.Restaurant)
+	}
+	return output
+}
+
+// streamChain returns the middleware chain shared by the restaurant streams.
+func streamChain[In any]() []infra.StreamEntry[In, *domain.Restaurant] {
+	return []infra.StreamEntry[In, *domain.Restaurant]{
+		infra.GatedStream(infra.MW_LOGGING, infra.StreamLogging[In, *domain.Restaurant](loggingCallback), infra.IsLoggingDisabled),
+		infra.GatedStream(infra.MW_TIMER, infra.StreamTimer[In, *domain.Restaurant](), infra.IsTimingDisabled),
+		infra.GatedStream(infra.MW_MASK_OUTPUT, infra.StreamMask[In](maskRestaurant), infra.IsMaskingDisabled),
+	}
+}
+
+func (f *RestaurantMiddlewareFactory) initStreams() {
+	f.StreamAllRestaurants = composeStream(f, OP_STREAM_ALL, bindStream(f, func(s domain.RestaurantStreamer, ctx context.Context, _ struct{}) iter.Seq2[*domain.Restaurant, error] {
+		return s.StreamAll(ctx)
+	}))
+	f.StreamRestaurantsByName = composeStream(f, OP_STREAM_BY_NAME, bindStream(f, domain.RestaurantStreamer.StreamByName))
+}
+
+// composeStream composes the stream chain around base and records its description.
+func composeStream[In any](f *RestaurantMiddlewareFactory, op string, base infra.StreamOp[In, *domain.Restaurant]) infra.StreamOp[In, *domain.Restaurant] {
+	stream, d := infra.ChainStreamEntries(base, streamChain[In]()...)
+	f.chains[op] = d
+	return stream
+}
+
+func bindStream[In any](f *RestaurantMiddlewareFactory, stream func(domain.RestaurantStreamer, context.Context, In) iter.Seq2[*domain.Restaurant, error]) infra.StreamOp[In, *domain.Restaurant] {
+	return func(ctx context.Context, in In) infra.StreamWithMeta[*domain.Restaurant] {
+		streamer, ok := f.RestaurantRepo.(domain.RestaurantStreamer)
+		if !ok {
+			return infra.StreamError[*domain.Restaurant](ErrNotSupported)
+		}
+		return infra.StreamWithMeta[*domain.Restaurant]{Items: stream(streamer, ctx, in)}
+	}
+}
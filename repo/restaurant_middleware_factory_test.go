@@ -3,11 +3,16 @@ package repo
 import (
 	"context"
 	"errors"
+	"fmt"
+	"iter"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/testingrepo/domain"
 	"github.com/testingrepo/infra"
+	"github.com/testingrepo/repo/memory"
+	"github.com/testingrepo/repo/repotest"
 )
 
 func TestNewRestaurantMiddlewareFactory(t *testing.T) {
@@ -15,9 +20,10 @@ func TestNewRestaurantMiddlewareFactory(t *testing.T) {
 	mockRepo := &mockRestaurantReader{}
 
 	// Act
-	factory := NewRestaurantMiddlewareFactory(mockRepo)
+	factory, err := NewRestaurantMiddlewareFactory(mockRepo)
 
 	// Assert
+	require.NoError(t, err)
 	assert.NotNil(t, factory)
 	assert.Equal(t, mockRepo, factory.RestaurantRepo)
 
@@ -94,7 +100,7 @@ func (m *mockRestaurantReader) FindByName(ctx context.Context, name string) ([]*
 	return nil, errors.New("not found")
 }
 
-func (m *mockRestaurantReader) FindByAddress(ctx context.Context, address string) ([]*domain.Restaurant, error) {
+func (m *mockRestaurantReader) FindByAddress(ctx context.Context, address domain.Address) ([]*domain.Restaurant, error) {
 	return nil, nil
 }
 
@@ -109,3 +115,285 @@ func (m *mockRestaurantReader) FindByRating(ctx context.Context, score int) ([]*
 func (m *mockRestaurantReader) FindByMenuItem(ctx context.Context, itemName string) ([]*domain.Restaurant, error) {
 	return nil, nil
 }
+
+func newFactory(t *testing.T, repo domain.RestaurantReader, opts ...FactoryOption) *RestaurantMiddlewareFactory {
+	t.Helper()
+	factory, err := NewRestaurantMiddlewareFactory(repo, opts...)
+	require.NoError(t, err)
+	return factory
+}
+
+func TestRestaurantMiddlewareFactoryDescribe(t *testing.T) {
+	factory := newFactory(t, &mockRestaurantReader{})
+
+	chains := factory.Describe()
+	assert.Len(t, chains, 17)
+	assert.NotNil(t, factory.FindRestaurantByMenuItem)
+
+	byName := chains[OP_FIND_BY_NAME]
+	assert.Empty(t, byName.Issues)
+	assert.Equal(t, infra.MW_LOGGING, byName.Entries[0].Name)
+	assert.Equal(t, "IsOutputResultDisabled", byName.Entries[2].Condition)
+	assert.Contains(t, byName.String(), "MaskOutput [unless IsMaskingDisabled] -> Retry")
+
+	// Output callback registered inside masking sees unmasked data.
+	builder := infra.MiddlewareBuilder[string, []*domain.Restaurant]{}
+	builder.AddNamed(infra.MW_MASK_OUTPUT, infra.MaskOutput[string](maskingCallback))
+	builder.AddNamed(infra.MW_OUTPUT_RESULT, infra.OutputResult[string](outputCallback))
+	issues := builder.Validate()
+	assert.Len(t, issues, 1)
+	assert.Equal(t, infra.MW_OUTPUT_RESULT, issues[0].Rule.Inner)
+}
+
+func TestRestaurantMiddlewareFactoryWithChain(t *testing.T) {
+	factory := newFactory(t, &mockRestaurantReader{},
+		WithChain(OP_FIND_BY_NAME, func(b *infra.MiddlewareBuilder[string, []*domain.Restaurant]) error {
+			return b.Remove(infra.MW_MASK_OUTPUT)
+		}),
+	)
+
+	chains := factory.Describe()
+	assert.Len(t, chains[OP_FIND_BY_NAME].Entries, 4)
+	assert.Len(t, chains[OP_FIND_BY_OWNER].Entries, 5)
+
+	output, err := factory.FindRestaurantByName(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "TEST@TEST.COM", output.Data[0].Email)
+}
+
+func TestRestaurantMiddlewareFactoryWithChainMisconfigured(t *testing.T) {
+	cases := []struct {
+		name string
+		opt  FactoryOption
+		want string
+	}{
+		{"customiser fails", WithChain(OP_FIND_BY_OWNER, func(b *infra.MiddlewareBuilder[string, []*domain.Restaurant]) error {
+			return b.Remove("does-not-exist")
+		}), "FindByOwner: middleware not found: does-not-exist"},
+		{"wrong input type", WithChain(OP_FIND_BY_RATING, func(b *infra.MiddlewareBuilder[string, []*domain.Restaurant]) error {
+			return nil
+		}), "FindByRating: customiser has the wrong type"},
+		{"wrong output type", WithChain(OP_AVERAGE_RATING, func(b *infra.MiddlewareBuilder[string, []*domain.Restaurant]) error {
+			return nil
+		}), "AverageRating: customiser has the wrong type"},
+		{"unknown operation", WithChain("FindByColour", func(b *infra.MiddlewareBuilder[string, []*domain.Restaurant]) error {
+			return nil
+		}), "customiser for unknown operation FindByColour"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			factory, err := NewRestaurantMiddlewareFactory(&mockRestaurantReader{}, tc.opt)
+			assert.Nil(t, factory)
+			assert.ErrorIs(t, err, ErrInvalidChain)
+			assert.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestRestaurantMiddlewareFactoryWithSampling(t *testing.T) {
+	factory := newFactory(t, &mockRestaurantReader{},
+		WithSampling(infra.SamplingPolicy{OnError: true}),
+	)
+
+	output, err := factory.FindRestaurantByName(context.Background(), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, false, output.Meta[infra.SAMPLED])
+
+	output, err = factory.FindRestaurantByName(context.Background(), "nonexistent")
+	assert.Error(t, err)
+	assert.Equal(t, true, output.Meta[infra.SAMPLED])
+	assert.Equal(t, infra.SAMPLE_REASON_ERROR, output.Meta[infra.SAMPLE_REASON])
+}
+
+func TestRestaurantMiddlewareFactoryRetryOverrides(t *testing.T) {
+	factory := newFactory(t, &mockRestaurantReader{})
+
+	ctx := infra.WithRetryOverride(context.Background(), 1, 0)
+	output, err := factory.FindRestaurantByName(ctx, "nonexistent")
+	assert.Error(t, err)
+	assert.Equal(t, 1, output.Meta[infra.RETRY_COUNT])
+
+	ctx = infra.DisableRetry(ctx)
+	assert.False(t, infra.IsTracingDisabled(ctx))
+	output, err = factory.FindRestaurantByName(ctx, "nonexistent")
+	assert.Error(t, err)
+	assert.NotContains(t, output.Meta, infra.RETRY_COUNT)
+}
+
+func TestRestaurantMiddlewareFactoryPagedNotSupported(t *testing.T) {
+	factory := newFactory(t, &mockRestaurantReader{})
+
+	out, err := factory.FindRestaurantByNamePaged(context.Background(), PagedInput[string]{Query: "test"})
+	assert.ErrorIs(t, err, ErrNotSupported)
+	assert.Equal(t, 0, out.Meta[infra.RETRY_COUNT], "ErrNotSupported is not retried")
+}
+
+func TestRestaurantMiddlewareFactoryStreams(t *testing.T) {
+	factory := newFactory(t, &mockRestaurantStreamer{})
+	assert.Equal(t, "Logging [unless IsLoggingDisabled] -> Timer [unless IsTimingDisabled] -> MaskOutput [unless IsMaskingDisabled] -> base",
+		factory.Describe()[OP_STREAM_ALL].String())
+	assert.Empty(t, factory.Describe()[OP_STREAM_BY_NAME].Issues)
+
+	stream := factory.StreamAllRestaurants(context.Background(), struct{}{})
+	count := 0
+	for r, err := range stream.Items {
+		assert.NoError(t, err)
+		assert.Equal(t, "****", r.Email)
+		count++
+	}
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2, stream.Meta[infra.ITEM_COUNT])
+	assert.Contains(t, stream.Meta, infra.DURATION)
+
+	notSupported := newFactory(t, &mockRestaurantReader{})
+	for _, err := range notSupported.StreamRestaurantsByName(context.Background(), "test").Items {
+		assert.ErrorIs(t, err, ErrNotSupported)
+	}
+}
+
+type mockRestaurantStreamer struct {
+	mockRestaurantReader
+}
+
+func (m *mockRestaurantStreamer) StreamAll(ctx context.Context) iter.Seq2[*domain.Restaurant, error] {
+	return func(yield func(*domain.Restaurant, error) bool) {
+		for range 2 {
+			found, _ := m.FindByName(ctx, "test")
+			if !yield(found[0], nil) {
+				return
+			}
+		}
+	}
+}
+
+func (m *mockRestaurantStreamer) StreamByName(ctx context.Context, name string) iter.Seq2[*domain.Restaurant, error] {
+	return m.StreamAll(ctx)
+}
+
+func TestRestaurantMiddlewareFactoryFindNear(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRestaurantRepo()
+	near := repotest.NewRestaurant("r1", "Near")
+	near.Address.Location = &domain.GeoPoint{Lat: 39.7990, Lng: -89.6440}
+	require.NoError(t, repo.InsertRestaurant(ctx, near))
+
+	factory := newFactory(t, repo)
+	out, err := factory.FindRestaurantsNear(ctx, NearInput{Point: domain.GeoPoint{Lat: 39.7817, Lng: -89.6501}, RadiusMeters: 5000})
+	require.NoError(t, err)
+	require.Len(t, out.Data, 1)
+	assert.Equal(t, "****", out.Data[0].Email)
+	assert.InDelta(t, 1996, out.Meta[NEAR_DISTANCES].([]float64)[0], 5)
+
+	_, err = newFactory(t, &mockRestaurantReader{}).FindRestaurantsNear(ctx, NearInput{})
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestRestaurantMiddlewareFactorySearchText(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRestaurantRepo()
+	require.NoError(t, repo.InsertRestaurant(ctx, repotest.NewRestaurant("r1", "Soup Kitchen")))
+	require.NoError(t, repo.InsertRestaurant(ctx, repotest.NewRestaurant("r2", "Noodle Bar")))
+
+	factory := newFactory(t, repo)
+	out, err := factory.SearchRestaurantsText(ctx, domain.TextQuery{Search: "soup"})
+	require.NoError(t, err)
+	require.Len(t, out.Data, 2)
+	assert.Equal(t, "r1", out.Data[0].ID)
+	scores := out.Meta[TEXT_SCORES].([]float64)
+	require.Len(t, scores, 2)
+	assert.Greater(t, scores[0], scores[1])
+
+	_, err = newFactory(t, &mockRestaurantReader{}).SearchRestaurantsText(ctx, domain.TextQuery{Search: "soup"})
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
+
+func TestRestaurantMiddlewareFactoryWithFallback(t *testing.T) {
+	ctx := infra.DisableRetry(context.Background())
+	snapshot := memory.NewRestaurantRepo()
+	require.NoError(t, snapshot.InsertRestaurant(ctx, repotest.NewRestaurant("r1", "nonexistent")))
+
+	factory := newFactory(t, &mockRestaurantReader{}, WithFallback(snapshot, nil))
+	assert.Equal(t, "Logging [unless IsLoggingDisabled] -> Timer [unless IsTimingDisabled] -> OutputResult [unless IsOutputResultDisabled] -> MaskOutput [unless IsMaskingDisabled] -> Fallback [unless IsFallbackDisabled] -> Retry [unless IsRetryDisabled] -> base",
+		factory.Describe()[OP_FIND_BY_NAME].String())
+	assert.Empty(t, factory.Describe()[OP_FIND_BY_NAME].Issues)
+
+	out, err := factory.FindRestaurantByName(ctx, "nonexistent")
+	require.NoError(t, err)
+	require.Len(t, out.Data, 1)
+	assert.Equal(t, "r1", out.Data[0].ID)
+	assert.Equal(t, true, out.Meta[infra.DEGRADED])
+	assert.Equal(t, "****", out.Data[0].Email, "fallback results are masked")
+
+	out, err = factory.FindRestaurantByName(ctx, "test")
+	require.NoError(t, err)
+	assert.Nil(t, out.Meta[infra.DEGRADED])
+
+	_, err = factory.FindRestaurantByName(infra.DisableFallback(ctx), "nonexistent")
+	assert.Error(t, err)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection reset"), true},
+		{ErrNotSupported, false},
+		{fmt.Errorf("find: %w", ErrNotFound), false},
+		{domain.ErrInvalidQuery, false},
+		{domain.ErrInvalidCursor, false},
+		{domain.ErrInvalidPoint, false},
+		{domain.ErrInvalidRadius, false},
+		{domain.ErrInvalidTextSearch, false},
+		{domain.ErrEmptyCriteria, false},
+		{context.Canceled, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, IsRetryable(tt.err), tt.err.Error())
+	}
+
+	out, err := newFactory(t, memory.NewRestaurantRepo()).
+		FindRestaurantByNamePaged(context.Background(), PagedInput[string]{Query: "x", Page: domain.PageRequest{Cursor: "not a cursor"}})
+	assert.ErrorIs(t, err, domain.ErrInvalidCursor)
+	assert.Equal(t, 0, out.Meta[infra.RETRY_COUNT], "validation errors are not retried")
+}
+
+func TestRestaurantMiddlewareFactoryAnalytics(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRestaurantRepo()
+	require.NoError(t, repo.InsertRestaurant(ctx, repotest.NewRestaurant("r1", "Soup Kitchen")))
+	factory := newFactory(t, repo,
+		WithSampling(infra.SamplingPolicy{OnError: true}),
+		WithChain(OP_MENU_PRICE_STATS, func(b *infra.MiddlewareBuilder[string, *domain.PriceStats]) error {
+			return b.Remove(infra.MW_RETRY)
+		}),
+	)
+
+	summary, err := factory.AverageRating(ctx, "r1")
+	require.NoError(t, err)
+	assert.Equal(t, domain.RatingSummary{RestaurantID: "r1", Average: 4, Count: 1}, *summary.Data)
+	assert.Equal(t, false, summary.Meta[infra.SAMPLED], "analytics logging follows WithSampling")
+
+	top, err := factory.TopRatedByCity(ctx, TopRatedInput{City: "Springfield"})
+	require.NoError(t, err)
+	require.Len(t, top.Data, 1)
+	assert.Equal(t, "****", top.Data[0].Restaurant.Email)
+	assert.Equal(t, "contact@r1.example", repo.Snapshot()[0].Email, "masking does not touch the stored restaurant")
+
+	stats, err := factory.MenuPriceStats(ctx, "r1")
+	require.NoError(t, err)
+	assert.Equal(t, 6.5, stats.Data.Max)
+	missing, err := factory.AverageRating(ctx, "missing")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, 0, missing.Meta[infra.RETRY_COUNT], "ErrNotFound is not retried")
+
+	chains := factory.Describe()
+	assert.NotContains(t, chains[OP_AVERAGE_RATING].String(), infra.MW_MASK_OUTPUT)
+	assert.NotContains(t, chains[OP_AVERAGE_RATING].String(), infra.MW_OUTPUT_RESULT)
+	assert.Contains(t, chains[OP_TOP_RATED_BY_CITY].String(), infra.MW_MASK_OUTPUT)
+	assert.Empty(t, chains[OP_TOP_RATED_BY_CITY].Issues)
+	assert.NotContains(t, chains[OP_MENU_PRICE_STATS].String(), infra.MW_RETRY)
+
+	_, err = newFactory(t, &mockRestaurantReader{}).AverageRating(ctx, "r1")
+	assert.ErrorIs(t, err, ErrNotSupported)
+}
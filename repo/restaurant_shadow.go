@@ -0,0 +1,24 @@
+package repo
+
+import (
+	"reflect"
+
+	"github.com/testingrepo/domain"
+	"github.com/testingrepo/infra"
+)
+
+// CompareRestaurants compares finder results by restaurant ID, ignoring order, for
+// use with infra.Shadow while migrating to a new store.
+var CompareRestaurants = infra.UnorderedSlices(
+	func(r *domain.Restaurant) string { return r.ID },
+	func(a, b *domain.Restaurant) bool { return reflect.DeepEqual(a, b) },
+)
+
+// CloneRestaurants deep copies finder results, see infra.ShadowConfig.Clone.
+func CloneRestaurants(rests []*domain.Restaurant) []*domain.Restaurant {
+	out := make([]*domain.Restaurant, len(rests))
+	for i, r := range rests {
+		out[i] = r.Clone()
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package repo
+
+import (
+	"context"
+
+	"github.com/testingrepo/domain"
+	"github.com/testingrepo/infra"
+)
+
+// Operation names of the writer chains.
+const (
+	OP_INSERT_RESTAURANT = "InsertRestaurant"
+	OP_UPDATE_MENU       = "UpdateMenu"
+	OP_ADD_RATING        = "AddRating"
+	OP_UPDATE_EMPLOYEE   = "UpdateEmployee"
+)
+
+type UpdateMenuInput struct {
+	ID   string
+	Menu []domain.MenuItem
+}
+
+type AddRatingInput struct {
+	ID     string
+	Rating domain.Rating
+}
+
+type UpdateEmployeeInput struct {
+	ID       string
+	Employee domain.Employee
+}
+
+// WriterFactoryOption customises a RestaurantWriterMiddlewareFactory.
+type WriterFactoryOption func(f *RestaurantWriterMiddlewareFactory)
+
+// WithSecondaryWriter dual-writes every successful primary write to secondary, handling
+// secondary failures according to mode. Used while migrating to a new backend.
+func WithSecondaryWriter(secondary domain.RestaurantWriter, mode infra.SecondaryFailureMode) WriterFactoryOption {
+	return func(f *RestaurantWriterMiddlewareFactory) {
+		f.Secondary = secondary
+		f.mode = mode
+	}
+}
+
+type RestaurantWriterMiddlewareFactory struct {
+	RestaurantRepo domain.RestaurantWriter
+	Secondary      domain.RestaurantWriter
+	mode           infra.SecondaryFailureMode
+	divergence     *infra.DivergenceTracker
+	chains         map[string]infra.ChainDescription
+	retries        []func(ctx context.Context) (int, int)
+
+	InsertRestaurant infra.RepoOp[*domain.Restaurant, struct{}]
+	UpdateMenu       infra.RepoOp[UpdateMenuInput, struct{}]
+	AddRating        infra.RepoOp[AddRatingInput, struct{}]
+	UpdateEmployee   infra.RepoOp[UpdateEmployeeInput, struct{}]
+}
+
+func NewRestaurantWriterMiddlewareFactory(repo domain.RestaurantWriter, opts ...WriterFactoryOption) *RestaurantWriterMiddlewareFactory {
+	f := &RestaurantWriterMiddlewareFactory{
+		RestaurantRepo: repo,
+		divergence:     infra.NewDivergenceTracker(),
+		chains:         make(map[string]infra.ChainDescription),
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+
+	f.InsertRestaurant = buildWriteChain(f, OP_INSERT_RESTAURANT,
+		func(r *domain.Restaurant) string { return r.ID },
+		func(w domain.RestaurantWriter) infra.RepoOp[*domain.Restaurant, struct{}] {
+			return bindWrite(func(ctx context.Context, r *domain.Restaurant) error { return w.InsertRestaurant(ctx, r) })
+		})
+	f.UpdateMenu = buildWriteChain(f, OP_UPDATE_MENU,
+		func(in UpdateMenuInput) string { return in.ID },
+		func(w domain.RestaurantWriter) infra.RepoOp[UpdateMenuInput, struct{}] {
+			return bindWrite(func(ctx context.Context, in UpdateMenuInput) error { return w.UpdateMenu(ctx, in.ID, in.Menu) })
+		})
+	f.AddRating = buildWriteChain(f, OP_ADD_RATING,
+		func(in AddRatingInput) string { return in.ID },
+		func(w domain.RestaurantWriter) infra.RepoOp[AddRatingInput, struct{}] {
+			return bindWrite(func(ctx context.Context, in AddRatingInput) error { return w.AddRating(ctx, in.ID, in.Rating) })
+		})
+	f.UpdateEmployee = buildWriteChain(f, OP_UPDATE_EMPLOYEE,
+		func(in UpdateEmployeeInput) string { return in.ID },
+		func(w domain.RestaurantWriter) infra.RepoOp[UpdateEmployeeInput, struct{}] {
+			return bindWrite(func(ctx context.Context, in UpdateEmployeeInput) error {
+				return w.UpdateEmployee(ctx, in.ID, in.Employee)
+			})
+		})
+	return f
+}
+
+// Describe returns the composed middleware chain of every write operation.
+func (f *RestaurantWriterMiddlewareFactory) Describe() map[string]infra.ChainDescription {
+	out := make(map[string]infra.ChainDescription, len(f.chains))
+	for op, d := range f.chains {
+		out[op] = d
+	}
+	return out
+}
+
+// Reconciliation lists the restaurants whose secondary copy may have diverged because
+// a secondary write failed and has not been retried successfully since.
+func (f *RestaurantWriterMiddlewareFactory) Reconciliation() infra.ReconciliationReport {
+	return f.divergence.Report()
+}
+
+// RetryQueued replays the secondary writes queued in infra.SecondaryQueue mode.
+func (f *RestaurantWriterMiddlewareFactory) RetryQueued(ctx context.Context) (succeeded, failed int) {
+	for _, retry := range f.retries {
+		s, fl := retry(ctx)
+		succeeded += s
+		failed += fl
+	}
+	return succeeded, failed
+}
+
+// writeChain returns the middleware chain shared by the restaurant writers. Writes are
+// not idempotent, so unlike the finders they are not retried.
+func writeChain[In any]() *infra.MiddlewareBuilder[In, struct{}] {
+	builder := &infra.MiddlewareBuilder[In, struct{}]{}
+	builder.AddGate(infra.MW_LOGGING, infra.Logging[In, struct{}](loggingCallback), infra.IsLoggingDisabled)
+	builder.AddGate(infra.MW_TIMER, infra.Timer[In, struct{}](), infra.IsTimingDisabled)
+	return builder
+}
+
+func buildWriteChain[In any](f *RestaurantWriterMiddlewareFactory, op string, key func(In) string, bind func(domain.RestaurantWriter) infra.RepoOp[In, struct{}]) infra.RepoOp[In, struct{}] {
+	builder := writeChain[In]()
+	if f.Secondary != nil {
+		secondary := bind(f.Secondary)
+		cfg := infra.DualWriteConfig[In, struct{}]{
+			Operation: op,
+			Mode:      f.mode,
+			Key:       key,
+			Tracker:   f.divergence,
+			Logger:    loggingCallback,
+		}
+		if f.mode == infra.SecondaryQueue {
+			cfg.Queue = infra.NewDualWriteQueue(secondary, f.divergence)
+			f.retries = append(f.retries, cfg.Queue.Retry)
+		}
+		builder.AddNamed(infra.MW_DUAL_WRITE, infra.DualWrite(secondary, cfg))
+	}
+	f.chains[op] = builder.Describe()
+	return builder.Build(bind(f.RestaurantRepo))
+}
+
+func bindWrite[In any](write func(ctx context.Context, in In) error) infra.RepoOp[In, struct{}] {
+	return func(ctx context.Context, in In) (infra.OutputWithMeta[struct{}], error) {
+		return infra.OutputWithMeta[struct{}]{}, write(ctx, in)
+	}
+}
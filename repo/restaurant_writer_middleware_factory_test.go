@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testingrepo/domain"
+	"github.com/testingrepo/infra"
+	"github.com/testingrepo/repo/memory"
+	"github.com/testingrepo/repo/repotest"
+)
+
+func TestRestaurantWriterMiddlewareFactoryDualWrite(t *testing.T) {
+	ctx := context.Background()
+	primary, secondary := memory.NewRestaurantRepo(), memory.NewRestaurantRepo()
+	// r1 predates the migration and only exists in the primary.
+	require.NoError(t, primary.InsertRestaurant(ctx, repotest.NewRestaurant("r1", "Old Place")))
+
+	f := NewRestaurantWriterMiddlewareFactory(primary, WithSecondaryWriter(secondary, infra.SecondaryQueue))
+	assert.Equal(t, "Logging [unless IsLoggingDisabled] -> Timer [unless IsTimingDisabled] -> DualWrite -> base", f.Describe()[OP_ADD_RATING].String())
+
+	_, err := f.InsertRestaurant(ctx, repotest.NewRestaurant("", "New Place"))
+	require.NoError(t, err)
+	inserted, err := secondary.FindByName(ctx, "New Place")
+	require.NoError(t, err)
+	require.Len(t, inserted, 1)
+	primaryCopy, err := primary.FindByName(ctx, "New Place")
+	require.NoError(t, err)
+	assert.Equal(t, primaryCopy[0].ID, inserted[0].ID)
+
+	out, err := f.AddRating(ctx, AddRatingInput{ID: "r1", Rating: domain.Rating{Score: 5, User: "gil"}})
+	require.NoError(t, err)
+	assert.Equal(t, infra.SECONDARY_WRITE_QUEUED, out.Meta[infra.SECONDARY_WRITE])
+	assert.Equal(t, []string{"r1"}, f.Reconciliation().DivergentIDs)
+
+	// Backfill the secondary, then drain the queue.
+	require.NoError(t, secondary.InsertRestaurant(ctx, repotest.NewRestaurant("r1", "Old Place")))
+	ok, failed := f.RetryQueued(ctx)
+	assert.Equal(t, 1, ok)
+	assert.Equal(t, 0, failed)
+	assert.Empty(t, f.Reconciliation().DivergentIDs)
+	backfilled, err := secondary.FindByName(ctx, "Old Place")
+	require.NoError(t, err)
+	assert.Len(t, backfilled[0].Ratings, 2)
+}
+
+func TestRestaurantWriterMiddlewareFactoryPrimaryOnly(t *testing.T) {
+	f := NewRestaurantWriterMiddlewareFactory(memory.NewRestaurantRepo())
+
+	_, err := f.UpdateMenu(context.Background(), UpdateMenuInput{ID: "missing"})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Equal(t, "Logging [unless IsLoggingDisabled] -> Timer [unless IsTimingDisabled] -> base", f.Describe()[OP_UPDATE_MENU].String())
+}